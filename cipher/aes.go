@@ -0,0 +1,66 @@
+package cipher
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"io"
+)
+
+// AES 使用 AES-256-GCM 加解密, 密文格式为 nonce + ciphertext
+type AES struct {
+	aead cipher.AEAD
+}
+
+// NewAES 创建 AES, key 为任意长度的口令, 经 sha256 后作为密钥
+func NewAES(key string) (*AES, error) {
+	sum := sha256.Sum256([]byte(key))
+	block, err := aes.NewCipher(sum[:])
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &AES{aead: aead}, nil
+}
+
+func (a *AES) Encrypt(plain []byte) ([]byte, error) {
+	nonce := make([]byte, a.aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return a.aead.Seal(nonce, nonce, plain, nil), nil
+}
+
+func (a *AES) Decrypt(data []byte) ([]byte, error) {
+	size := a.aead.NonceSize()
+	if len(data) < size {
+		return nil, errors.New("ciphertext too short")
+	}
+	return a.aead.Open(nil, data[:size], data[size:], nil)
+}
+
+func (a *AES) EncryptToBase64(plain string) (string, error) {
+	bytes, err := a.Encrypt([]byte(plain))
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(bytes), nil
+}
+
+func (a *AES) DecryptBase64(s string) (string, error) {
+	bytes, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return "", err
+	}
+	ret, err := a.Decrypt(bytes)
+	if err != nil {
+		return "", err
+	}
+	return string(ret), nil
+}
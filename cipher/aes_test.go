@@ -0,0 +1,21 @@
+package cipher
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAES(t *testing.T) {
+	a, err := NewAES("passphrase")
+	assert.NoError(t, err)
+	s, err := a.EncryptToBase64("123456")
+	assert.NoError(t, err)
+	plain, err := a.DecryptBase64(s)
+	assert.NoError(t, err)
+	assert.Equal(t, "123456", plain)
+
+	b, _ := NewAES("other")
+	_, err = b.DecryptBase64(s)
+	assert.Error(t, err)
+}
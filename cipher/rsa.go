@@ -78,6 +78,17 @@ func (r *RSA) Decrypt(cipher []byte) ([]byte, error) {
 	if block == nil || block.Type != TypePrivateKey {
 		return nil, fmt.Errorf("failed to decode pem block containing private key, rest: %s", string(rest))
 	}
+	if r.pkcs8() {
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		pri, ok := key.(*rsa.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("not a rsa private key: %T", key)
+		}
+		return rsa.DecryptPKCS1v15(rand.Reader, pri, cipher)
+	}
 	pri, err := x509.ParsePKCS1PrivateKey(block.Bytes)
 	if err != nil {
 		return nil, err
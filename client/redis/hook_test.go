@@ -5,9 +5,11 @@ import (
 	"errors"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/go-redis/redis/v8"
 	"github.com/neura-flow/common/log"
+	"github.com/neura-flow/common/log/logtest"
 
 	"github.com/prometheus/client_golang/prometheus"
 	io_prometheus_client "github.com/prometheus/client_model/go"
@@ -84,6 +86,21 @@ func TestHook(t *testing.T) {
 			"redis_client_size",
 		}, filter(metrics, "redis_client"))
 	})
+
+	t.Run("log slow commands", func(t *testing.T) {
+		logger := logtest.New()
+		hook := NewHook(&Config{Metrics: MetricsConfig{SlowLogMinCost: 10}}, logger)
+
+		cmd := redis.NewStringCmd(context.TODO(), "get", "foo")
+		ctx := context.WithValue(context.TODO(), startKey{}, time.Now().Add(-time.Second))
+		assert.Nil(hook.AfterProcess(ctx, cmd))
+		logger.AssertLogged(t, log.LevelWarn, "RedisSlowLog")
+
+		logger.Reset()
+		ctx, _ = hook.BeforeProcess(context.TODO(), cmd)
+		assert.Nil(hook.AfterProcess(ctx, cmd))
+		logger.AssertNotLogged(t, log.LevelWarn, "RedisSlowLog")
+	})
 }
 
 func filter(metrics []*io_prometheus_client.MetricFamily, namespace string) []string {
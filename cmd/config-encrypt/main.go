@@ -0,0 +1,79 @@
+// config-encrypt 加密配置值, 输出可直接写入配置文件的 ENC(...) 字符串, 用法:
+//
+//	config-encrypt -gen 2048 -out ./keys          # 生成 public.pem/private.pem
+//	config-encrypt -pub ./keys/public.pem 123456  # 加密参数中的值
+//	echo -n 123456 | config-encrypt -pub ./keys/public.pem
+//
+// 应用通过 cipher.keyfile (或环境变量 CIPHER_KEYFILE) 指定私钥后, 加载配置时自动解密.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/neura-flow/common/cipher"
+	"github.com/neura-flow/common/config"
+)
+
+func main() {
+	pub := flag.String("pub", os.Getenv("CIPHER_PUBKEY"), "public key file")
+	gen := flag.Int("gen", 0, "generate a rsa key pair with the given bits")
+	pkcs8 := flag.Bool("pkcs8", false, "generate private key in pkcs8 format")
+	out := flag.String("out", ".", "output dir of the generated key pair")
+	flag.Parse()
+
+	var err error
+	if *gen > 0 {
+		err = genKeys(*gen, *pkcs8, *out)
+	} else {
+		err = encrypt(*pub, flag.Args())
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func genKeys(bits int, pkcs8 bool, dir string) error {
+	pub, pri, err := cipher.GenRSA(bits, pkcs8)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	if err = os.WriteFile(filepath.Join(dir, "public.pem"), pub, 0644); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, "private.pem"), pri, 0600)
+}
+
+func encrypt(pubFile string, args []string) error {
+	if pubFile == "" {
+		return fmt.Errorf("public key file is required, use -pub")
+	}
+	pub, err := os.ReadFile(pubFile)
+	if err != nil {
+		return err
+	}
+	var plain string
+	if len(args) > 0 {
+		plain = strings.Join(args, " ")
+	} else {
+		buf, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return err
+		}
+		plain = strings.TrimRight(string(buf), "\r\n")
+	}
+	s, err := config.Encrypt(cipher.NewRSA(&cipher.RSAConfig{PublicKey: string(pub)}), plain)
+	if err != nil {
+		return err
+	}
+	fmt.Println(s)
+	return nil
+}
@@ -1,11 +1,14 @@
 package config
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"os"
+	"path/filepath"
 	"reflect"
 	"strings"
+	"sync"
 
 	"github.com/gin-gonic/gin"
 	"github.com/hashicorp/consul/api"
@@ -16,6 +19,7 @@ import (
 	kenv "github.com/knadh/koanf/providers/env"
 	"github.com/knadh/koanf/providers/rawbytes"
 	"github.com/knadh/koanf/v2"
+	"github.com/neura-flow/common/cipher"
 	"github.com/neura-flow/common/util"
 )
 
@@ -29,6 +33,7 @@ const (
 	DefaultVaultPrefix   = "secret"
 	AppPort              = "app.port"
 	AppFile              = "app.file"
+	AppProfile           = "app.profile"
 	ConsulAddress        = "consul.address"
 	ConsulPrefix         = "consul.prefix"
 	ConsulEnabled        = "consul.enabled"
@@ -40,6 +45,15 @@ const (
 	GinMode              = "gin.mode"
 )
 
+// Source 名称, 用于记录配置项来源
+const (
+	SourceFlag   = "flag"
+	SourceEnv    = "env"
+	SourceFile   = "file"
+	SourceConsul = "consul"
+	SourceVault  = "vault"
+)
+
 type Option = func(*Config)
 
 func WithCfgVar(n string) Option {
@@ -48,18 +62,50 @@ func WithCfgVar(n string) Option {
 	}
 }
 
+// WithFlagSet 设置 BindFlags 使用的 FlagSet, 已解析的 FlagSet 不会再次解析
+func WithFlagSet(fs *flag.FlagSet) Option {
+	return func(c *Config) {
+		c.flagSet = fs
+	}
+}
+
+// WithErrorHandler 设置后台任务(如配置热加载)的错误处理函数
+func WithErrorHandler(h func(err error)) Option {
+	return func(c *Config) {
+		c.errorHandler = h
+	}
+}
+
 type Config struct {
+	mu     sync.RWMutex
 	k      *koanf.Koanf
+	base   *koanf.Koanf // ReadConfig 之前已加载的配置(如 flags), 每次重新加载都以它为基础
 	parser *kjson.JSON
 
-	cfgVar string
+	cfgVar       string
+	flagSet      *flag.FlagSet
+	boundFlags   *flag.FlagSet // BindFlags 加载的 FlagSet, 每次重新加载时在所有 Source 之后再次加载
+	registry     *registry
+	errorHandler func(err error)
+	subscribers  []*subscriber
+
+	resolvers     map[string]Resolver
+	noInterpolate bool
+	rsa           *cipher.RSA
+
+	encrypted map[string]bool // 由 ENC(...) 解密得到的配置项
+	sensitive map[string]bool // 由占位符引用了敏感值的配置项
+
+	origins     map[string]string // 配置项 -> 最后写入该配置项的 Source 名称
+	baseOrigins map[string]string
 }
 
 func New(opts ...Option) *Config {
 	c := &Config{
-		k:      koanf.New("."),
-		parser: kjson.Parser(),
-		cfgVar: AppFile,
+		k:        koanf.New("."),
+		parser:   kjson.Parser(),
+		cfgVar:   AppFile,
+		registry: defaultRegistry.clone(),
 	}
 	for _, opt := range opts {
 		opt(c)
@@ -69,8 +115,9 @@ func New(opts ...Option) *Config {
 
 func NewFromKoanf(k *koanf.Koanf) *Config {
 	return &Config{
-		k:      k,
-		parser: kjson.Parser(),
+		k:        k,
+		parser:   kjson.Parser(),
+		registry: defaultRegistry.clone(),
 	}
 }
 
@@ -135,64 +182,125 @@ func (c *Config) BindAndReadConfig(def interface{}) error {
 	return c.ReadConfig()
 }
 
-// BindFlags 根据结构体def中的定义的 json 属性绑定到 Flags, 然后解析 flags 并读取
+// BindFlags 根据结构体def中的定义的 json 属性绑定到 Flags (默认为全局 FlagSet, 见 WithFlagSet),
+// 未解析时解析命令行参数, 然后读取用户显式设置的 flags. 显式设置的 flags 优先级最高, 会覆盖 env、file 以及远程配置中的值
 func (c *Config) BindFlags(def interface{}) error {
-	InitFlags(def)
-	return NewFlagSource(c.k).Load()
+	fs := c.flagSet
+	if fs == nil {
+		fs = flag.CommandLine
+	}
+	BindFlagSet(fs, "", def)
+	if !fs.Parsed() {
+		if err := fs.Parse(os.Args[1:]); err != nil {
+			return err
+		}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.origins == nil {
+		c.origins = make(map[string]string)
+	}
+	c.boundFlags = fs
+	return loadInto(c.k, c.origins, SourceFlag, func(target *koanf.Koanf) Source {
+		return NewFlagSetSource(target, fs)
+	})
 }
 
+// ReadConfig 按优先级加载已注册的 Source (默认 env -> file -> consul -> vault), 加载完成后原子替换当前配置
 func (c *Config) ReadConfig() error {
-	s := []Source{
-		NewEnvSource(c.k),
-		NewFileSource(c.k, c.string(c.configFileVar(), DefaultCfgFile)),
+	c.mu.Lock()
+	if c.base == nil {
+		c.base = c.k.Copy()
+		c.baseOrigins = copyOrigins(c.origins)
+	}
+	c.mu.Unlock()
+	return c.Reload()
+}
+
+// Reload 基于 ReadConfig 之前的配置重新加载所有 Source, 然后再次加载显式设置的 flags,
+// 解密 ENC(...) 值(见 decrypt.go)并解析占位符(见 interpolate.go), 成功后原子替换并通知订阅者, 失败时保留当前配置
+func (c *Config) Reload() error {
+	c.mu.RLock()
+	base, origins, fs := c.base, copyOrigins(c.baseOrigins), c.boundFlags
+	c.mu.RUnlock()
+	if base == nil {
+		return fmt.Errorf("config has not been read")
+	}
+	k := base.Copy()
+	for _, def := range c.sourceDefs() {
+		if err := loadInto(k, origins, def.name, func(target *koanf.Koanf) Source {
+			return def.new(k, target)
+		}); err != nil {
+			return err
+		}
 	}
-	if err := c.load(s); err != nil {
-		return err
+	if fs != nil {
+		if err := loadInto(k, origins, SourceFlag, func(target *koanf.Koanf) Source {
+			return NewFlagSetSource(target, fs)
+		}); err != nil {
+			return err
+		}
 	}
-
-	s = []Source{}
-	if c.k.Bool(ConsulEnabled) {
-		s = append(s, c.newConsulSource())
+	encrypted, err := c.decrypt(k)
+	if err != nil {
+		return err
 	}
-	if c.k.Bool(VaultEnabled) {
-		s = append(s, c.newVaultSource())
+	sensitive, err := c.interpolate(k, origins, func(key string) bool {
+		return IsSensitive(Origin{Key: key, Source: origins[key], Encrypted: encrypted[key]})
+	})
+	if err != nil {
+		return err
 	}
-	return c.load(s)
+	c.swap(k, origins, encrypted, sensitive)
+	return nil
 }
 
-func (c *Config) load(s []Source) error {
-	for _, item := range s {
-		if err := item.Load(); err != nil {
-			return err
-		}
+// loadInto 把 Source 加载到独立的 koanf 后合并到 k, 并记录其写入的配置项来源
+func loadInto(k *koanf.Koanf, origins map[string]string, name string, newSource func(target *koanf.Koanf) Source) error {
+	target := koanf.New(".")
+	s := newSource(target)
+	if s == nil {
+		return nil
 	}
-	return nil
+	if err := s.Load(); err != nil {
+		return err
+	}
+	for _, key := range target.Keys() {
+		origins[key] = name
+	}
+	return k.Merge(target)
 }
 
-func (c *Config) newConsulSource() Source {
+func (c *Config) newConsulSource(k, target *koanf.Koanf) Source {
 	return NewConsulSource(
-		c.k,
-		c.string(ConsulAddress, DefaultConsulAddress),
-		c.k.String(ConsulToken),
-		c.string(ConsulPrefix, DefaultConsulPrefix),
-		c.AppName(),
-		c.k.Bool(ConsulEnabled),
+		target,
+		kstring(k, ConsulAddress, DefaultConsulAddress),
+		c.bootstrapString(k, ConsulToken),
+		kstring(k, ConsulPrefix, DefaultConsulPrefix),
+		c.appName(k),
+		k.Bool(ConsulEnabled),
+		c.remoteOptions(k, ConsulTimeout, ConsulRetries, ConsulBackoff)...,
 	)
 }
 
-func (c *Config) newVaultSource() Source {
+func (c *Config) newVaultSource(k, target *koanf.Koanf) Source {
 	return NewVaultSource(
-		c.k,
-		c.string(VaultAddress, DefaultVaultAddress),
-		c.k.String(strings.Replace(VaultToken, ".", "_", -1)),
-		c.string(VaultPrefix, DefaultVaultPrefix),
-		c.AppName(),
-		c.k.Bool(VaultEnabled),
+		target,
+		kstring(k, VaultAddress, DefaultVaultAddress),
+		c.bootstrapString(k, strings.Replace(VaultToken, ".", "_", -1)),
+		kstring(k, VaultPrefix, DefaultVaultPrefix),
+		c.appName(k),
+		k.Bool(VaultEnabled),
+		append(c.remoteOptions(k, VaultTimeout, VaultRetries, VaultBackoff), WithVaultKV(k.Int(VaultKV)))...,
 	)
 }
 
 func (c *Config) string(key string, def string) string {
-	v := strings.Trim(c.k.String(key), " ")
+	return kstring(c.Koanf(), key, def)
+}
+
+func kstring(k *koanf.Koanf, key string, def string) string {
+	v := strings.Trim(k.String(key), " ")
 	if v != "" {
 		return v
 	}
@@ -207,11 +315,15 @@ func (c *Config) configFileVar() string {
 }
 
 func (c *Config) AppName() string {
-	return c.string(c.configFileVar(), DefaultAppName)
+	return c.appName(c.Koanf())
+}
+
+func (c *Config) appName(k *koanf.Koanf) string {
+	return kstring(k, c.configFileVar(), DefaultAppName)
 }
 
 func (c *Config) AppPort() int {
-	if v := c.k.Int(AppPort); v > 0 {
+	if v := c.Koanf().Int(AppPort); v > 0 {
 		return v
 	}
 	return DefaultAppPort
@@ -233,22 +345,29 @@ func (c *Config) AppFile() string {
 	return c.string(AppFile, DefaultCfgFile)
 }
 
-// Dump find key and unmarshal to target
+// Dump find key and unmarshal to target, then fill defaults and validate target by struct tags, see Validate
 func (c *Config) Dump(key string, target interface{}) error {
 	var b []byte
 	var err error
+	k := c.Koanf()
 	if key == "" {
-		b, err = c.k.Marshal(c.parser)
+		b, err = k.Marshal(c.parser)
 	} else {
-		b, err = jsoniter.Marshal(c.k.Get(key))
+		b, err = jsoniter.Marshal(k.Get(key))
 	}
 	if err != nil {
 		return err
 	}
-	return jsoniter.Unmarshal(b, target)
+	if err = jsoniter.Unmarshal(b, target); err != nil {
+		return err
+	}
+	return Validate(key, target)
 }
 
+// Koanf 返回当前配置快照, 热加载时会被整体替换, 调用方不应长期持有
 func (c *Config) Koanf() *koanf.Koanf {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
 	return c.k
 }
 
@@ -271,87 +390,145 @@ type KeyValue struct {
 }
 
 type flagSource struct {
-	k *koanf.Koanf
+	k  *koanf.Koanf
+	fs *flag.FlagSet
 }
 
+// NewFlagSource 加载全局 FlagSet 中由用户显式设置的 flags
 func NewFlagSource(k *koanf.Koanf) Source {
+	return NewFlagSetSource(k, flag.CommandLine)
+}
+
+// NewFlagSetSource 加载 fs 中由用户显式设置的 flags, 未设置的 flags 不会写入, 以免默认零值覆盖配置文件中的值
+func NewFlagSetSource(k *koanf.Koanf, fs *flag.FlagSet) Source {
 	return &flagSource{
-		k: k,
+		k:  k,
+		fs: fs,
 	}
 }
 
 func (s *flagSource) Load() error {
-	kvm := make(map[string]string, 10)
-	flag.VisitAll(func(f *flag.Flag) {
-		kvm[f.Name] = f.Value.String()
+	kvm := make(map[string]interface{}, 10)
+	s.fs.Visit(func(f *flag.Flag) {
+		if g, ok := f.Value.(flag.Getter); ok {
+			kvm[f.Name] = g.Get()
+		} else {
+			kvm[f.Name] = f.Value.String()
+		}
 	})
-	buf, _ := jsoniter.Marshal(kvm)
-	if err := s.k.Load(rawbytes.Provider(buf), kjson.Parser()); err != nil {
-		return err
-	}
-	return nil
+	return loadFlat(s.k, kvm)
 }
 
+// InitFlags 在全局 FlagSet 上绑定 v 中的字段并解析命令行参数, 见 BindFlagSet
 func InitFlags(v interface{}) {
-	initFlags(reflect.ValueOf(v))
+	BindFlagSet(flag.CommandLine, "", v)
 	flag.Parse()
 }
 
-func initFlags(v reflect.Value) {
+// BindFlagSet 根据结构体 v 的 json tag 在 fs 上定义 flags, 嵌套结构体的 flag 名称以 '.' 连接, 如 pool.maxOpen,
+// 字段当前值作为默认值, desc tag 作为说明. 支持基础类型、time.Duration、types.Duration、[]string (逗号分隔或多次指定)、
+// map[string]string (k1=v1,k2=v2) 及它们的指针
+func BindFlagSet(fs *flag.FlagSet, prefix string, v interface{}) {
+	initFlags(fs, prefix, reflect.ValueOf(v))
+}
+
+func initFlags(fs *flag.FlagSet, prefix string, v reflect.Value) {
 	v = reflect.Indirect(v)
 	rt := v.Type()
 	for i := 0; i < v.NumField(); i++ {
-		f := v.Field(i)
 		ft := rt.Field(i)
-		f = reflect.Indirect(f)
-		tag := ft.Tag.Get("json")
-		tag = strings.Split(tag, ",")[0]
-		var name = tag
-		if f.Kind() == reflect.Struct {
-			initFlags(f)
-		} else {
-			desc := ft.Tag.Get("desc")
-			switch f.Kind() {
-			case reflect.String:
-				flag.String(name, f.String(), desc)
-			case reflect.Bool:
-				flag.Bool(name, f.Bool(), desc)
-			case reflect.Int, reflect.Int64, reflect.Int16, reflect.Int8, reflect.Int32:
-				flag.Int64(name, f.Int(), desc)
-			case reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uint:
-				flag.Uint64(name, f.Uint(), desc)
-			case reflect.Float32, reflect.Float64:
-				flag.Float64(name, f.Float(), desc)
-			default:
-
+		if ft.PkgPath != "" {
+			continue
+		}
+		name := strings.Split(ft.Tag.Get("json"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		f := v.Field(i)
+		if f.Kind() == reflect.Ptr {
+			if f.IsNil() {
+				f = reflect.New(f.Type().Elem())
 			}
+			f = f.Elem()
+		}
+		if ft.Anonymous && name == "" && f.Kind() == reflect.Struct {
+			initFlags(fs, prefix, f)
+			continue
+		}
+		if name == "" {
+			name = util.Lcfirst(ft.Name)
+		}
+		name = join(prefix, name)
+		if fs.Lookup(name) != nil {
+			continue
+		}
+		desc := ft.Tag.Get("desc")
+		if value := newFlagValue(f); value != nil {
+			fs.Var(value, name, desc)
+			continue
+		}
+		switch f.Kind() {
+		case reflect.Struct:
+			initFlags(fs, name, f)
+		case reflect.String:
+			fs.String(name, f.String(), desc)
+		case reflect.Bool:
+			fs.Bool(name, f.Bool(), desc)
+		case reflect.Int, reflect.Int64, reflect.Int16, reflect.Int8, reflect.Int32:
+			fs.Int64(name, f.Int(), desc)
+		case reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uint:
+			fs.Uint64(name, f.Uint(), desc)
+		case reflect.Float32, reflect.Float64:
+			fs.Float64(name, f.Float(), desc)
+		default:
+
 		}
 	}
 }
 
 type fileSource struct {
-	config string
-	k      *koanf.Koanf
+	config   string
+	profiles []string
+	k        *koanf.Koanf
 }
 
-func NewFileSource(k *koanf.Koanf, config string) Source {
+// NewFileSource 加载配置文件及其 include 的文件, 格式由扩展名决定(yaml/json/toml/properties, 默认 yaml),
+// 然后按顺序叠加 profile 文件, 如 config.yaml 的 profile dev 对应 config-dev.yaml, 不存在时忽略
+func NewFileSource(k *koanf.Koanf, config string, profiles ...string) Source {
 	return &fileSource{
-		k:      k,
-		config: config,
+		k:        k,
+		config:   config,
+		profiles: profiles,
 	}
 }
 
 func (f *fileSource) Load() error {
-	if f.config != "" {
-		buf, err := os.ReadFile(f.config)
-		if err != nil {
-			return err
+	_, err := f.load(f.k)
+	return err
+}
+
+// load 加载所有文件, 返回已加载的文件和可能存在的 profile 文件路径
+func (f *fileSource) load(k *koanf.Koanf) ([]string, error) {
+	if f.config == "" {
+		return nil, nil
+	}
+	var paths []string
+	if err := loadFile(k, f.config, map[string]bool{}, &paths); err != nil {
+		return nil, err
+	}
+	for _, profile := range f.profiles {
+		path := profileFile(f.config, profile)
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			if abs, err := filepath.Abs(path); err == nil {
+				paths = append(paths, abs)
+			}
+			continue
 		}
-		if err = f.k.Load(rawbytes.Provider(buf), kyaml.Parser()); err != nil {
-			return err
+		if err := loadFile(k, path, map[string]bool{}, &paths); err != nil {
+			return nil, err
 		}
 	}
-	return nil
+	return paths, nil
 }
 
 func LoadFile(path string, dst interface{}) error {
@@ -391,9 +568,10 @@ type consulSource struct {
 	prefix  string
 	appName string
 	enabled bool
+	opts    *remoteOptions
 }
 
-func NewConsulSource(k *koanf.Koanf, address, token, prefix, appName string, enabled bool) Source {
+func NewConsulSource(k *koanf.Koanf, address, token, prefix, appName string, enabled bool, opts ...RemoteOption) Source {
 	return &consulSource{
 		k:       k,
 		address: address,
@@ -401,6 +579,7 @@ func NewConsulSource(k *koanf.Koanf, address, token, prefix, appName string, ena
 		prefix:  prefix,
 		appName: appName,
 		enabled: enabled,
+		opts:    newRemoteOptions(opts),
 	}
 }
 
@@ -408,36 +587,44 @@ func (s *consulSource) Load() error {
 	if !s.enabled {
 		return nil
 	}
+	client, err := s.client()
+	if err != nil {
+		return err
+	}
+	data, err := s.opts.fetch(SourceConsul+"-"+s.appName, func(ctx context.Context) (map[string]string, error) {
+		return s.readConsul(ctx, client, s.prefixes())
+	})
+	if err != nil {
+		return err
+	}
+	return loadStrings(s.k, data)
+}
+
+func (s *consulSource) client() (*api.Client, error) {
 	config := api.DefaultConfig()
 	config.Address = s.address
 	if strings.Trim(s.token, " ") != "" {
 		config.Token = strings.Trim(s.token, " ")
 	}
-	client, err := api.NewClient(config)
-	if err != nil {
-		return err
-	}
+	return api.NewClient(config)
+}
 
-	prefixes := []string{s.prefix + "/application/", s.prefix + "/" + s.appName + "/"}
-	return s.readConsul(client, prefixes)
+func (s *consulSource) prefixes() []string {
+	return []string{s.prefix + "/application/", s.prefix + "/" + s.appName + "/"}
 }
 
-func (s *consulSource) readConsul(client *api.Client, prefixes []string) error {
+func (s *consulSource) readConsul(ctx context.Context, client *api.Client, prefixes []string) (map[string]string, error) {
 	data := make(map[string]string, 150)
 	for _, key := range prefixes {
-		pairs, _, err := client.KV().List(key, &api.QueryOptions{})
+		pairs, _, err := client.KV().List(key, (&api.QueryOptions{}).WithContext(ctx))
 		if err != nil {
-			return err
+			return nil, err
 		}
 		for _, pair := range pairs {
 			data[pair.Key[len(key):]] = string(pair.Value)
 		}
 	}
-	buf, _ := jsoniter.Marshal(data)
-	if err := s.k.Load(rawbytes.Provider(buf), kjson.Parser()); err != nil {
-		return err
-	}
-	return nil
+	return data, nil
 }
 
 type vaultSource struct {
@@ -447,16 +634,19 @@ type vaultSource struct {
 	prefix  string
 	appName string
 	enabled bool
+	opts    *remoteOptions
 }
 
-func NewVaultSource(k *koanf.Koanf, address, token, prefix, appName string, enabled bool) Source {
+// NewVaultSource 读取 vault 中 prefix/application 以及 prefix/appName 下的 secret, kv v2 时读取 prefix/data/...
+func NewVaultSource(k *koanf.Koanf, address, token, prefix, appName string, enabled bool, opts ...RemoteOption) Source {
 	return &vaultSource{
 		k:       k,
 		address: address,
 		token:   token,
-		prefix:  prefix,
+		prefix:  strings.TrimSuffix(prefix, "/"),
 		appName: appName,
 		enabled: enabled,
+		opts:    newRemoteOptions(opts),
 	}
 }
 
@@ -465,27 +655,52 @@ func (s *vaultSource) Load() error {
 		return nil
 	}
 	client, err := vaultApi.NewClient(&vaultApi.Config{
-		Address: s.address,
+		Address:    s.address,
+		Timeout:    s.opts.timeout,
+		MaxRetries: 0,
 	})
 	if err != nil {
 		return err
 	}
 	client.SetToken(s.token)
-	prefixes := []string{s.prefix + "/application", "secret/" + s.appName}
-	return s.readVault(client, prefixes)
+	data, err := s.opts.fetch(SourceVault+"-"+s.appName, func(ctx context.Context) (map[string]string, error) {
+		return s.readVault(ctx, client, s.paths())
+	})
+	if err != nil {
+		return err
+	}
+	return loadStrings(s.k, data)
+}
+
+func (s *vaultSource) paths() []string {
+	names := []string{"application", s.appName}
+	paths := make([]string, 0, len(names))
+	for _, name := range names {
+		if s.opts.kvVersion == 2 {
+			paths = append(paths, s.prefix+"/data/"+name)
+		} else {
+			paths = append(paths, s.prefix+"/"+name)
+		}
+	}
+	return paths
 }
 
-func (s *vaultSource) readVault(client *vaultApi.Client, prefixes []string) error {
+func (s *vaultSource) readVault(ctx context.Context, client *vaultApi.Client, paths []string) (map[string]string, error) {
 	data := make(map[string]string, 100)
-	for _, key := range prefixes {
-		secret, err := client.Logical().Read(key)
+	for _, path := range paths {
+		secret, err := client.Logical().ReadWithContext(ctx, path)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		if secret == nil || secret.Data == nil {
 			continue
 		}
-		for k, v := range secret.Data {
+		values := secret.Data
+		if s.opts.kvVersion == 2 {
+			nested, _ := values["data"].(map[string]interface{})
+			values = nested
+		}
+		for k, v := range values {
 			switch v.(type) {
 			case string:
 				data[k] = v.(string)
@@ -496,11 +711,13 @@ func (s *vaultSource) readVault(client *vaultApi.Client, prefixes []string) erro
 			}
 		}
 	}
+	return data, nil
+}
+
+// loadStrings 加载 consul/vault 中读取的 kv
+func loadStrings(k *koanf.Koanf, data map[string]string) error {
 	buf, _ := jsoniter.Marshal(data)
-	if err := s.k.Load(rawbytes.Provider(buf), kjson.Parser()); err != nil {
-		return err
-	}
-	return nil
+	return k.Load(rawbytes.Provider(buf), kjson.Parser())
 }
 
 // ArgsDef 应用启动所需的 args
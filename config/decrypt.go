@@ -0,0 +1,142 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/knadh/koanf/v2"
+	"github.com/neura-flow/common/cipher"
+)
+
+// 加密配置值使用的 RSA 私钥, 也可通过环境变量 CIPHER_KEY / CIPHER_KEYFILE / CIPHER_FORMAT 设置, 例如:
+//
+//	mysql:
+//	  password: ENC(SnpDNk9nR0swTHJC...)
+const (
+	CipherKey     = "cipher.key"     // pem 格式的私钥内容
+	CipherKeyFile = "cipher.keyfile" // 私钥文件路径
+	CipherFormat  = "cipher.format"  // 私钥格式 pkcs1/pkcs8, 默认 pkcs1
+
+	encPrefix = "ENC("
+	encSuffix = ")"
+)
+
+// WithRSA 设置解密 ENC(...) 配置值使用的 RSA, 设置后忽略 cipher.key/cipher.keyfile
+func WithRSA(r *cipher.RSA) Option {
+	return func(c *Config) {
+		c.rsa = r
+	}
+}
+
+// IsEncrypted 判断配置值是否为 ENC(...) 形式的密文
+func IsEncrypted(v string) bool {
+	v = strings.TrimSpace(v)
+	return strings.HasPrefix(v, encPrefix) && strings.HasSuffix(v, encSuffix)
+}
+
+// Encrypt 使用公钥加密 plain, 返回可直接写入配置文件的 ENC(base64) 字符串
+func Encrypt(r *cipher.RSA, plain string) (string, error) {
+	s, err := r.EncryptToBase64(plain)
+	if err != nil {
+		return "", err
+	}
+	return encPrefix + s + encSuffix, nil
+}
+
+// Decrypt 解密 ENC(base64) 字符串, 非密文原样返回
+func Decrypt(r *cipher.RSA, v string) (string, error) {
+	if !IsEncrypted(v) {
+		return v, nil
+	}
+	v = strings.TrimSpace(v)
+	return r.DecryptBase64(strings.TrimSpace(v[len(encPrefix) : len(v)-len(encSuffix)]))
+}
+
+// decrypt 解密 k 中所有 ENC(...) 形式的配置值, 返回被解密的配置项
+func (c *Config) decrypt(k *koanf.Koanf) (map[string]bool, error) {
+	encrypted := make(map[string]bool)
+	var r *cipher.RSA
+	for _, key := range k.Keys() {
+		var v interface{}
+		switch raw := k.Get(key).(type) {
+		case string:
+			if !IsEncrypted(raw) {
+				continue
+			}
+			v = raw
+		case []interface{}:
+			found := false
+			for _, item := range raw {
+				if s, ok := item.(string); ok && IsEncrypted(s) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				continue
+			}
+			v = raw
+		default:
+			continue
+		}
+		if r == nil {
+			var err error
+			if r, err = c.decrypter(k); err != nil {
+				return nil, fmt.Errorf("config key %q is encrypted: %w", key, err)
+			}
+		}
+		plain, err := decryptValue(r, v)
+		if err != nil {
+			return nil, fmt.Errorf("config key %q: failed to decrypt: %w", key, err)
+		}
+		if err = k.Set(key, plain); err != nil {
+			return nil, err
+		}
+		encrypted[key] = true
+	}
+	return encrypted, nil
+}
+
+func decryptValue(r *cipher.RSA, v interface{}) (interface{}, error) {
+	list, ok := v.([]interface{})
+	if !ok {
+		return Decrypt(r, v.(string))
+	}
+	ret := make([]interface{}, len(list))
+	for i, item := range list {
+		ret[i] = item
+		if s, ok := item.(string); ok {
+			plain, err := Decrypt(r, s)
+			if err != nil {
+				return nil, err
+			}
+			ret[i] = plain
+		}
+	}
+	return ret, nil
+}
+
+// decrypter 返回解密使用的 RSA, 私钥来自 WithRSA 或已加载的 cipher.key/cipher.keyfile
+func (c *Config) decrypter(k *koanf.Koanf) (*cipher.RSA, error) {
+	if c.rsa != nil {
+		return c.rsa, nil
+	}
+	key := k.String(CipherKey)
+	if key == "" {
+		file := k.String(CipherKeyFile)
+		if file == "" {
+			return nil, errors.New("no private key configured, set cipher.key or cipher.keyfile")
+		}
+		buf, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		key = string(buf)
+	}
+	return cipher.NewRSA(&cipher.RSAConfig{
+		PrivateKey: key,
+		Format:     k.String(CipherFormat),
+	}), nil
+}
@@ -0,0 +1,86 @@
+package config
+
+import (
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/neura-flow/common/cipher"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfig_Decrypt(t *testing.T) {
+	pub, pri, err := cipher.GenRSA(cipher.RSABits1024, true)
+	assert.NoError(t, err)
+	enc, err := Encrypt(cipher.NewRSA(&cipher.RSAConfig{PublicKey: string(pub)}), "123456")
+	assert.NoError(t, err)
+	assert.True(t, IsEncrypted(enc))
+
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, "private.pem")
+	assert.NoError(t, os.WriteFile(keyFile, pri, 0600))
+	file := filepath.Join(dir, "config.yaml")
+	assert.NoError(t, os.WriteFile(file, []byte(`mysql:
+  host: db
+  pass: `+enc+`
+  dsn: root:${mysql.pass}@tcp(${mysql.host})
+`), 0644))
+	t.Setenv("APP_FILE", file)
+	t.Setenv("CIPHER_KEYFILE", keyFile)
+	t.Setenv("CIPHER_FORMAT", cipher.FormatPkcs8)
+
+	c := New()
+	assert.NoError(t, c.ReadConfig())
+	assert.Equal(t, "123456", c.Koanf().String("mysql.pass"))
+	assert.Equal(t, "root:123456@tcp(db)", c.Koanf().String("mysql.dsn"))
+	list := c.Effective("mysql.pass")
+	assert.True(t, list[0].Encrypted)
+	assert.Equal(t, RedactedValue, list[0].Value)
+}
+
+func TestConfig_EffectiveInterpolatedSecret(t *testing.T) {
+	dir := t.TempDir()
+	secretFile := filepath.Join(dir, "redis_pass")
+	assert.NoError(t, os.WriteFile(secretFile, []byte("s3cret\n"), 0600))
+	file := filepath.Join(dir, "config.yaml")
+	assert.NoError(t, os.WriteFile(file, []byte(`mysql:
+  host: db
+  password: "123456"
+  dsn: root:${mysql.password}@tcp(${mysql.host})
+  url: mysql://${mysql.dsn}
+redis:
+  addr: redis://:${file:`+secretFile+`}@cache
+  host: ${env:REDIS_HOST_FOR_TEST}
+`), 0644))
+	t.Setenv("APP_FILE", file)
+	t.Setenv("REDIS_HOST_FOR_TEST", "cache")
+
+	c := New()
+	assert.NoError(t, c.ReadConfig())
+	assert.Equal(t, "redis://:s3cret@cache", c.Koanf().String("redis.addr"))
+	values := make(map[string]interface{})
+	for _, o := range c.Effective("") {
+		values[o.Key] = o.Value
+	}
+	// 引用了密码或 file 中的值的配置项被屏蔽, 引用普通配置项和环境变量的不受影响
+	assert.Equal(t, RedactedValue, values["mysql.dsn"])
+	assert.Equal(t, RedactedValue, values["mysql.url"])
+	assert.Equal(t, RedactedValue, values["redis.addr"])
+	assert.Equal(t, "db", values["mysql.host"])
+	assert.Equal(t, "cache", values["redis.host"])
+
+	w := httptest.NewRecorder()
+	c.Handler()(w, httptest.NewRequest("GET", "/config?key=mysql", nil))
+	assert.NotContains(t, w.Body.String(), "123456")
+}
+
+func TestConfig_DecryptWithoutKey(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	assert.NoError(t, os.WriteFile(file, []byte("redis:\n  pass: ENC(YWJj)\n"), 0644))
+	t.Setenv("APP_FILE", file)
+
+	c := New()
+	assert.EqualError(t, c.ReadConfig(),
+		`config key "redis.pass" is encrypted: no private key configured, set cipher.key or cipher.keyfile`)
+}
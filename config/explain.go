@@ -0,0 +1,101 @@
+package config
+
+import (
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+const (
+	// SourceUnknown 非 Source 加载的配置项, 如 NewFromYaml 等直接写入的值
+	SourceUnknown = "unknown"
+	RedactedValue = "******"
+)
+
+// sensitiveKey 匹配需要脱敏的配置项名称
+var sensitiveKey = regexp.MustCompile(`(?i)(password|passwd|pwd|token|secret|credential|private_?key|dsn)`)
+
+// secretKeys 无论来源和名称总是脱敏的配置项
+var secretKeys = map[string]bool{
+	CipherKey:     true,
+	CipherKeyFile: true,
+	CacheKey:      true,
+	ConsulToken:   true,
+	strings.Replace(VaultToken, ".", "_", -1): true,
+}
+
+// Origin 配置项的生效值及来源
+type Origin struct {
+	Key    string      `json:"key"`
+	Value  interface{} `json:"value"`
+	Source string      `json:"source"`
+	// Encrypted 配置值是否由 ENC(...) 解密得到
+	Encrypted bool `json:"encrypted,omitempty"`
+	// Sensitive 配置值是否由占位符引用了敏感的配置项或 file/vault 等外部值
+	Sensitive bool `json:"sensitive,omitempty"`
+}
+
+// Explain 返回 key 对应配置项(或子树下所有配置项)的生效值及最后写入它的 Source, 按 key 排序
+func (c *Config) Explain(key string) []Origin {
+	c.mu.RLock()
+	k, origins, encrypted, sensitive := c.k, c.origins, c.encrypted, c.sensitive
+	c.mu.RUnlock()
+
+	list := make([]Origin, 0)
+	for _, item := range k.Keys() {
+		if key != "" && item != key && !strings.HasPrefix(item, key+".") {
+			continue
+		}
+		source, ok := origins[item]
+		if !ok {
+			source = SourceUnknown
+		}
+		list = append(list, Origin{Key: item, Value: k.Get(item), Source: source, Encrypted: encrypted[item], Sensitive: sensitive[item]})
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Key < list[j].Key
+	})
+	return list
+}
+
+// Effective 返回脱敏后的生效配置, 来自 vault 或解密得到的值、引用了这些值的配置项以及名称包含 password/token/secret 等的配置项会被屏蔽
+func (c *Config) Effective(key string) []Origin {
+	list := c.Explain(key)
+	for i := range list {
+		if IsSensitive(list[i]) {
+			list[i].Value = RedactedValue
+		}
+	}
+	return list
+}
+
+// IsSensitive 判断配置项是否需要脱敏
+func IsSensitive(o Origin) bool {
+	return o.Source == SourceVault || o.Encrypted || o.Sensitive || secretKeys[strings.ToLower(o.Key)] || sensitiveKey.MatchString(o.Key)
+}
+
+// Handler 以 json 格式输出脱敏后的生效配置, 可通过 query 参数 key 过滤, 例如:
+//
+//	server.Engine().GET("/config", gin.WrapF(cfg.Handler()))
+func (c *Config) Handler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		buf, err := jsoniter.Marshal(c.Effective(r.URL.Query().Get("key")))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		_, _ = w.Write(buf)
+	}
+}
+
+func copyOrigins(origins map[string]string) map[string]string {
+	m := make(map[string]string, len(origins))
+	for k, v := range origins {
+		m[k] = v
+	}
+	return m
+}
@@ -0,0 +1,107 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	kyaml "github.com/knadh/koanf/parsers/yaml"
+	"github.com/knadh/koanf/providers/rawbytes"
+	"github.com/knadh/koanf/v2"
+	"github.com/neura-flow/common/util"
+)
+
+// IncludeKey 配置文件中用于引用其他文件的配置项, 支持单个路径或列表, 相对路径基于当前文件所在目录, 支持通配符:
+//
+//	include:
+//	  - common.yaml
+//	  - conf.d/*.yaml
+const IncludeKey = "include"
+
+// loadFile 先按顺序加载 include 的文件, 再加载文件自身覆盖它们, visiting 用于检测循环引用
+func loadFile(k *koanf.Koanf, path string, visiting map[string]bool, paths *[]string) error {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	if visiting[abs] {
+		return fmt.Errorf("circular include of config file: %s", path)
+	}
+	visiting[abs] = true
+	defer delete(visiting, abs)
+
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	tmp := koanf.New(".")
+	if err = tmp.Load(rawbytes.Provider(buf), fileParser(path)); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	includes, err := includeFiles(filepath.Dir(path), tmp.Get(IncludeKey))
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	for _, include := range includes {
+		if err = loadFile(k, include, visiting, paths); err != nil {
+			return err
+		}
+	}
+	tmp.Delete(IncludeKey)
+	*paths = append(*paths, abs)
+	return k.Merge(tmp)
+}
+
+func includeFiles(dir string, v interface{}) ([]string, error) {
+	var patterns []string
+	switch val := v.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		patterns = []string{val}
+	case []interface{}:
+		for _, item := range val {
+			patterns = append(patterns, fmt.Sprint(item))
+		}
+	default:
+		return nil, fmt.Errorf("invalid %s: %v", IncludeKey, v)
+	}
+	var files []string
+	for _, pattern := range patterns {
+		if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(dir, pattern)
+		}
+		if !strings.ContainsAny(pattern, "*?[") {
+			files = append(files, pattern)
+			continue
+		}
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, err
+		}
+		sort.Strings(matches)
+		files = append(files, matches...)
+	}
+	return files, nil
+}
+
+// fileParser 根据扩展名选择解析器, 无法识别时按 yaml 解析
+func fileParser(path string) koanf.Parser {
+	if p, err := parserFor(filepath.Ext(path)); err == nil {
+		return p
+	}
+	return kyaml.Parser()
+}
+
+// profileFile 返回 profile 对应的文件, 如 ./configs/config.yaml 的 dev 对应 ./configs/config-dev.yaml
+func profileFile(file, profile string) string {
+	ext := filepath.Ext(file)
+	return strings.TrimSuffix(file, ext) + "-" + profile + ext
+}
+
+// profiles 返回 app.profile 中逗号分隔的 profile 列表
+func profiles(k *koanf.Koanf) []string {
+	return util.ExpandAddress([]string{k.String(AppProfile)})
+}
@@ -0,0 +1,85 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/knadh/koanf/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		assert.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+}
+
+func TestFileSource_IncludeAndProfile(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"config.yaml":          "include:\n  - common.json\n  - conf.d/*\ndb:\n  host: base\n",
+		"common.json":          `{"db":{"host":"common","port":3306},"app":{"name":"common"}}`,
+		"conf.d/a.toml":        "[redis]\naddrs = \"127.0.0.1:6379\"\ndb = 1\n",
+		"conf.d/b.properties":  "# comment\nredis.db=2\nredis.kind = simple\n",
+		"config-dev.yaml":      "db:\n  host: dev\n",
+		"config-override.yaml": "include: extra.yaml\n",
+		"extra.yaml":           "app:\n  name: extra\n",
+		"unused/config-x.yaml": "db:\n  host: unused\n",
+	})
+	k := koanf.New(".")
+	assert.NoError(t, NewFileSource(k, filepath.Join(dir, "config.yaml"), "dev", "missing", "override").Load())
+	assert.Equal(t, "dev", k.String("db.host"))
+	assert.Equal(t, 3306, k.Int("db.port"))
+	assert.Equal(t, "127.0.0.1:6379", k.String("redis.addrs"))
+	assert.Equal(t, "2", k.String("redis.db"))
+	assert.Equal(t, "simple", k.String("redis.kind"))
+	assert.Equal(t, "extra", k.String("app.name"))
+	assert.False(t, k.Exists(IncludeKey))
+}
+
+func TestFileSource_CircularInclude(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"a.yaml": "include: b.yaml\n",
+		"b.yaml": "include: a.yaml\n",
+	})
+	err := NewFileSource(koanf.New("."), filepath.Join(dir, "a.yaml")).Load()
+	assert.ErrorContains(t, err, "circular include")
+}
+
+func TestConfig_ReadConfigProfile(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"config.yaml":      "a: base\nb: base\n",
+		"config-prod.yaml": "a: prod\n",
+	})
+	t.Setenv("APP_FILE", filepath.Join(dir, "config.yaml"))
+	t.Setenv("APP_PROFILE", "prod")
+	c := New()
+	assert.NoError(t, c.ReadConfig())
+	assert.Equal(t, "prod", c.Koanf().String("a"))
+	assert.Equal(t, "base", c.Koanf().String("b"))
+}
+
+func TestPropertiesParser(t *testing.T) {
+	m, err := PropertiesParser().Unmarshal([]byte("a.b=1\na.c: x=y\n! comment\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"a": map[string]interface{}{"b": "1", "c": "x=y"}}, m)
+	buf, err := PropertiesParser().Marshal(m)
+	assert.NoError(t, err)
+	assert.Equal(t, "a.b=1\na.c=x=y\n", string(buf))
+	// 冲突的 key 总是由层级深的覆盖, 与加载顺序无关
+	for i := 0; i < 20; i++ {
+		m, err = PropertiesParser().Unmarshal([]byte("a=1\na.b=2\nc.d=3\nc=4\n"))
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]interface{}{
+			"a": map[string]interface{}{"b": "2"},
+			"c": map[string]interface{}{"d": "3"},
+		}, m)
+	}
+	_, err = PropertiesParser().Unmarshal([]byte("invalid"))
+	assert.Error(t, err)
+}
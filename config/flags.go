@@ -0,0 +1,147 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/neura-flow/common/types"
+)
+
+var (
+	typesDurationType = reflect.TypeOf(types.Duration(""))
+	stringSliceType   = reflect.TypeOf([]string(nil))
+	stringMapType     = reflect.TypeOf(map[string]string(nil))
+)
+
+// newFlagValue 返回非基础类型字段对应的 flag.Value, 不支持时返回 nil
+func newFlagValue(f reflect.Value) flag.Value {
+	switch f.Type() {
+	case durationType:
+		return &durationValue{d: time.Duration(f.Int())}
+	case typesDurationType:
+		return &typesDurationValue{s: f.String()}
+	case stringSliceType:
+		return &stringSliceValue{list: f.Interface().([]string)}
+	case stringMapType:
+		return &stringMapValue{m: f.Interface().(map[string]string)}
+	}
+	return nil
+}
+
+type durationValue struct {
+	d time.Duration
+}
+
+func (v *durationValue) Set(s string) error {
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	v.d = d
+	return nil
+}
+
+func (v *durationValue) String() string {
+	return v.d.String()
+}
+
+func (v *durationValue) Get() interface{} {
+	return v.d
+}
+
+// typesDurationValue 校验格式后以字符串保存, 与 types.Duration 的 json 格式一致
+type typesDurationValue struct {
+	s string
+}
+
+func (v *typesDurationValue) Set(s string) error {
+	if _, err := time.ParseDuration(s); err != nil {
+		return err
+	}
+	v.s = s
+	return nil
+}
+
+func (v *typesDurationValue) String() string {
+	return v.s
+}
+
+func (v *typesDurationValue) Get() interface{} {
+	return v.s
+}
+
+// stringSliceValue 支持逗号分隔, 多次指定时追加, 第一次指定时覆盖默认值
+type stringSliceValue struct {
+	list []string
+	set  bool
+}
+
+func (v *stringSliceValue) Set(s string) error {
+	if !v.set {
+		v.list, v.set = nil, true
+	}
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			v.list = append(v.list, item)
+		}
+	}
+	return nil
+}
+
+func (v *stringSliceValue) String() string {
+	if v == nil {
+		return ""
+	}
+	return strings.Join(v.list, ",")
+}
+
+func (v *stringSliceValue) Get() interface{} {
+	return v.list
+}
+
+// stringMapValue 格式 k1=v1,k2=v2, 多次指定时合并, 第一次指定时覆盖默认值
+type stringMapValue struct {
+	m   map[string]string
+	set bool
+}
+
+func (v *stringMapValue) Set(s string) error {
+	if !v.set {
+		v.m, v.set = make(map[string]string), true
+	}
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item == "" {
+			continue
+		}
+		key, val, ok := strings.Cut(item, "=")
+		if !ok || strings.TrimSpace(key) == "" {
+			return fmt.Errorf("invalid map item %q, should be key=value", item)
+		}
+		v.m[strings.TrimSpace(key)] = strings.TrimSpace(val)
+	}
+	return nil
+}
+
+func (v *stringMapValue) String() string {
+	if v == nil {
+		return ""
+	}
+	keys := make([]string, 0, len(v.m))
+	for k := range v.m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	items := make([]string, 0, len(keys))
+	for _, k := range keys {
+		items = append(items, k+"="+v.m[k])
+	}
+	return strings.Join(items, ",")
+}
+
+func (v *stringMapValue) Get() interface{} {
+	return v.m
+}
@@ -0,0 +1,97 @@
+package config
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/neura-flow/common/types"
+	"github.com/stretchr/testify/assert"
+)
+
+type flagsConfig struct {
+	Name     string            `json:"name" desc:"name"`
+	Port     int               `json:"port"`
+	Debug    *bool             `json:"debug"`
+	Timeout  time.Duration     `json:"timeout"`
+	Period   types.Duration    `json:"period"`
+	Tags     []string          `json:"tags"`
+	Labels   map[string]string `json:"labels"`
+	Pool     types.Pool        `json:"pool"`
+	Redis    *flagsRedis       `json:"redis"`
+	Internal string            `json:"-"`
+}
+
+type flagsRedis struct {
+	Addrs string `json:"addrs"`
+}
+
+func TestBindFlagSet(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	BindFlagSet(fs, "", &flagsConfig{Name: "def", Tags: []string{"a"}})
+	for _, name := range []string{"name", "port", "debug", "timeout", "period", "tags", "labels",
+		"pool.maxOpen", "pool.maxIdle", "redis.addrs"} {
+		assert.NotNil(t, fs.Lookup(name), name)
+	}
+	assert.Nil(t, fs.Lookup("Internal"))
+	assert.Equal(t, "def", fs.Lookup("name").DefValue)
+	assert.Equal(t, "a", fs.Lookup("tags").DefValue)
+	assert.Error(t, fs.Parse([]string{"-timeout", "abc"}))
+}
+
+func TestConfig_BindFlags(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	assert.NoError(t, os.WriteFile(file, []byte("name: file\nport: 8080\npool:\n  maxIdle: 5\n"), 0644))
+	t.Setenv("APP_FILE", file)
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.String(AppFile, "", "")
+	BindFlagSet(fs, "", &flagsConfig{})
+	assert.NoError(t, fs.Parse([]string{
+		"-debug", "-timeout", "3s", "-period", "1m", "-tags", "a,b", "-tags", "c",
+		"-labels", "k1=v1,k2=v2", "-pool.maxOpen", "10", "-redis.addrs", "127.0.0.1:6379",
+	}))
+
+	c := New(WithFlagSet(fs))
+	assert.NoError(t, c.BindAndReadConfig(&flagsConfig{}))
+	var cfg flagsConfig
+	assert.NoError(t, c.Dump("", &cfg))
+	assert.Equal(t, "file", cfg.Name)
+	assert.Equal(t, 8080, cfg.Port)
+	assert.True(t, *cfg.Debug)
+	assert.Equal(t, 3*time.Second, cfg.Timeout)
+	assert.Equal(t, types.Duration("1m"), cfg.Period)
+	assert.Equal(t, []string{"a", "b", "c"}, cfg.Tags)
+	assert.Equal(t, map[string]string{"k1": "v1", "k2": "v2"}, cfg.Labels)
+	assert.Equal(t, 10, cfg.Pool.MaxOpen)
+	assert.Equal(t, 5, cfg.Pool.MaxIdle)
+	assert.Equal(t, "127.0.0.1:6379", cfg.Redis.Addrs)
+	assert.Equal(t, SourceFlag, c.Explain("pool.maxOpen")[0].Source)
+}
+
+func TestConfig_FlagsOverrideSources(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	assert.NoError(t, os.WriteFile(file, []byte("name: file\nport: 8080\npool:\n  maxOpen: 5\n"), 0644))
+	t.Setenv("APP_FILE", file)
+	t.Setenv("POOL_MAXOPEN", "20")
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.String(AppFile, "", "")
+	BindFlagSet(fs, "", &flagsConfig{})
+	assert.NoError(t, fs.Parse([]string{"-name", "flag", "-pool.maxOpen", "10"}))
+
+	c := New(WithFlagSet(fs))
+	assert.NoError(t, c.BindAndReadConfig(&flagsConfig{}))
+	// 显式设置的 flags 覆盖 file 和 env 中的值, 未设置的仍使用 file 中的值
+	assert.Equal(t, "flag", c.Koanf().String("name"))
+	assert.Equal(t, 10, c.Koanf().Int("pool.maxOpen"))
+	assert.Equal(t, 8080, c.Koanf().Int("port"))
+	assert.Equal(t, SourceFlag, c.Explain("name")[0].Source)
+
+	assert.NoError(t, os.WriteFile(file, []byte("name: changed\nport: 9090\n"), 0644))
+	assert.NoError(t, c.Reload())
+	assert.Equal(t, "flag", c.Koanf().String("name"))
+	assert.Equal(t, 9090, c.Koanf().Int("port"))
+}
@@ -0,0 +1,277 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	vaultApi "github.com/hashicorp/vault/api"
+	"github.com/knadh/koanf/v2"
+)
+
+// 占位符中引用外部值的 scheme, 例如:
+//
+//	${db.host}                      # 引用其他配置项
+//	${db.port:-3306}                # 配置项不存在时使用默认值
+//	${ENV:DB_HOST:-127.0.0.1}       # 环境变量
+//	${file:/run/secrets/db_pass}    # 文件内容(去掉首尾空白)
+//	${vault:secret/data/db#pass}    # vault 中 path 对应 secret 的字段, 支持 kv v1/v2
+//	$${literal}                     # 转义, 输出 ${literal}
+const (
+	SchemeEnv   = "env"
+	SchemeFile  = "file"
+	SchemeVault = "vault"
+)
+
+// Resolver 解析 ${scheme:ref} 形式的占位符
+type Resolver func(ref string) (string, error)
+
+// WithResolver 注册自定义 scheme 的占位符解析器
+func WithResolver(scheme string, r Resolver) Option {
+	return func(c *Config) {
+		if c.resolvers == nil {
+			c.resolvers = make(map[string]Resolver)
+		}
+		c.resolvers[strings.ToLower(scheme)] = r
+	}
+}
+
+// WithInterpolation 是否在加载完成后解析配置值中的占位符, 默认开启
+func WithInterpolation(enabled bool) Option {
+	return func(c *Config) {
+		c.noInterpolate = !enabled
+	}
+}
+
+// interpolate 解析 k 中所有字符串配置值的占位符, origins 为配置项的来源, secret 判断配置项本身是否敏感,
+// 返回引用了敏感配置项或 env 以外 scheme 的配置项, 用于 Effective 脱敏
+func (c *Config) interpolate(k *koanf.Koanf, origins map[string]string, secret func(key string) bool) (map[string]bool, error) {
+	if c.noInterpolate {
+		return nil, nil
+	}
+	it := &interpolator{
+		k:         k,
+		resolvers: c.schemeResolvers(k),
+		resolved:  make(map[string]interface{}),
+		secret:    secret,
+		sensitive: make(map[string]bool),
+	}
+	for _, key := range k.Keys() {
+		v, err := it.resolveKey(key, nil)
+		if err != nil {
+			// 环境变量的值可能本身包含 ${...}(如 shell 模板), 无法解析时保留原值
+			if origins[key] == SourceEnv {
+				continue
+			}
+			return nil, err
+		}
+		if v != nil {
+			if err = k.Set(key, v); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return it.sensitive, nil
+}
+
+func (c *Config) schemeResolvers(k *koanf.Koanf) map[string]Resolver {
+	resolvers := c.localResolvers()
+	if _, ok := resolvers[SchemeVault]; !ok {
+		resolvers[SchemeVault] = newVaultResolver(kstring(k, VaultAddress, DefaultVaultAddress), c.bootstrapString(k, strings.Replace(VaultToken, ".", "_", -1)))
+	}
+	return resolvers
+}
+
+// localResolvers 返回不依赖远程服务的解析器(env、file 以及自定义的 scheme)
+func (c *Config) localResolvers() map[string]Resolver {
+	resolvers := map[string]Resolver{
+		SchemeEnv:  resolveEnv,
+		SchemeFile: resolveFile,
+	}
+	for scheme, r := range c.resolvers {
+		resolvers[scheme] = r
+	}
+	return resolvers
+}
+
+type interpolator struct {
+	k         *koanf.Koanf
+	resolvers map[string]Resolver
+	resolved  map[string]interface{} // 已解析的配置项, nil 表示不包含占位符
+	secret    func(key string) bool  // 配置项本身是否敏感
+	sensitive map[string]bool        // 引用了敏感值的配置项
+}
+
+// markSensitive 标记 key 引用了敏感值
+func (it *interpolator) markSensitive(key string) {
+	if it.sensitive != nil {
+		it.sensitive[key] = true
+	}
+}
+
+// resolveKey 返回配置项解析后的值, 不包含占位符时返回 nil, stack 为当前的引用链, 用于检测循环引用
+func (it *interpolator) resolveKey(key string, stack []string) (interface{}, error) {
+	if v, ok := it.resolved[key]; ok {
+		return v, nil
+	}
+	for i, item := range stack {
+		if item == key {
+			return nil, fmt.Errorf("config key %q: circular reference %s", stack[0], strings.Join(append(stack[i:], key), " -> "))
+		}
+	}
+	stack = append(stack, key)
+	var ret interface{}
+	switch v := it.k.Get(key).(type) {
+	case string:
+		if strings.Contains(v, "${") {
+			s, err := it.expand(v, stack)
+			if err != nil {
+				return nil, err
+			}
+			ret = s
+		}
+	case []interface{}:
+		list := make([]interface{}, len(v))
+		changed := false
+		for i, item := range v {
+			list[i] = item
+			if s, ok := item.(string); ok && strings.Contains(s, "${") {
+				expanded, err := it.expand(s, stack)
+				if err != nil {
+					return nil, err
+				}
+				list[i], changed = expanded, true
+			}
+		}
+		if changed {
+			ret = list
+		}
+	}
+	it.resolved[key] = ret
+	return ret, nil
+}
+
+// expand 替换字符串中的所有占位符, 整个字符串只有一个配置项引用时保留被引用值的类型
+func (it *interpolator) expand(s string, stack []string) (interface{}, error) {
+	var buf strings.Builder
+	for {
+		start := strings.Index(s, "${")
+		if start < 0 {
+			buf.WriteString(s)
+			break
+		}
+		if start > 0 && s[start-1] == '$' {
+			buf.WriteString(s[:start-1] + "${")
+			s = s[start+2:]
+			continue
+		}
+		end := strings.IndexByte(s[start:], '}')
+		if end < 0 {
+			return nil, fmt.Errorf("config key %q: unclosed placeholder in %q", stack[len(stack)-1], s)
+		}
+		end += start
+		v, err := it.placeholder(s[start+2:end], stack)
+		if err != nil {
+			return nil, err
+		}
+		if start == 0 && end == len(s)-1 && buf.Len() == 0 {
+			return v, nil
+		}
+		buf.WriteString(s[:start])
+		buf.WriteString(fmt.Sprint(v))
+		s = s[end+1:]
+	}
+	return buf.String(), nil
+}
+
+func (it *interpolator) placeholder(expr string, stack []string) (interface{}, error) {
+	key := stack[len(stack)-1]
+	ref, def, hasDef := strings.Cut(expr, ":-")
+	if scheme, name, ok := strings.Cut(ref, ":"); ok {
+		if r, ok := it.resolvers[strings.ToLower(scheme)]; ok {
+			v, err := r(name)
+			if err == nil {
+				// file/vault 等通常用于读取密钥, 只有环境变量视为普通值
+				if !strings.EqualFold(scheme, SchemeEnv) {
+					it.markSensitive(key)
+				}
+				return v, nil
+			}
+			if hasDef {
+				return def, nil
+			}
+			return nil, fmt.Errorf("config key %q: unresolved ${%s}: %w", key, expr, err)
+		}
+	}
+	if !it.k.Exists(ref) {
+		if hasDef {
+			return def, nil
+		}
+		return nil, fmt.Errorf("config key %q: unresolved ${%s}", key, expr)
+	}
+	v, err := it.resolveKey(ref, stack)
+	if err != nil {
+		return nil, err
+	}
+	if it.sensitive[ref] || (it.secret != nil && it.secret(ref)) {
+		it.markSensitive(key)
+	}
+	if v == nil {
+		v = it.k.Get(ref)
+	}
+	return v, nil
+}
+
+var errNotFound = errors.New("not found")
+
+func resolveEnv(name string) (string, error) {
+	if v, ok := os.LookupEnv(name); ok {
+		return v, nil
+	}
+	return "", errNotFound
+}
+
+func resolveFile(path string) (string, error) {
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(buf)), nil
+}
+
+// newVaultResolver 解析 path#field, 首次使用时才创建 vault 客户端
+func newVaultResolver(address, token string) Resolver {
+	var client *vaultApi.Client
+	return func(ref string) (string, error) {
+		path, field, ok := strings.Cut(ref, "#")
+		if !ok || field == "" {
+			return "", fmt.Errorf("vault reference should be path#field")
+		}
+		if client == nil {
+			cli, err := vaultApi.NewClient(&vaultApi.Config{Address: address})
+			if err != nil {
+				return "", err
+			}
+			cli.SetToken(token)
+			client = cli
+		}
+		secret, err := client.Logical().Read(path)
+		if err != nil {
+			return "", err
+		}
+		if secret == nil || secret.Data == nil {
+			return "", errNotFound
+		}
+		data := secret.Data
+		// kv v2 的数据在 data 字段中
+		if nested, ok := data["data"].(map[string]interface{}); ok {
+			data = nested
+		}
+		v, ok := data[field]
+		if !ok {
+			return "", errNotFound
+		}
+		return fmt.Sprint(v), nil
+	}
+}
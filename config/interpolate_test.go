@@ -0,0 +1,88 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/neura-flow/common/client/mysql"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfig_Interpolate(t *testing.T) {
+	dir := t.TempDir()
+	secret := filepath.Join(dir, "db_pass")
+	assert.NoError(t, os.WriteFile(secret, []byte("s3cret\n"), 0600))
+	file := filepath.Join(dir, "config.yaml")
+	assert.NoError(t, os.WriteFile(file, []byte(`shared:
+  host: 10.0.0.1
+  port: 3306
+mysql:
+  addr: ${shared.host}:${shared.port}
+  username: ${ENV:DB_USER:-root}
+  password: ${file:`+secret+`}
+  db: ${shared.db:-test}
+  pool:
+    maxOpen: ${shared.port}
+literal: $${shared.host}
+list:
+  - ${shared.host}
+  - plain
+`), 0644))
+	t.Setenv("APP_FILE", file)
+	t.Setenv("DB_USER", "admin")
+
+	c := New()
+	assert.NoError(t, c.ReadConfig())
+	var cfg mysql.Config
+	assert.NoError(t, c.Dump("mysql", &cfg))
+	assert.Equal(t, "10.0.0.1:3306", cfg.Addr)
+	assert.Equal(t, "admin", cfg.Username)
+	assert.Equal(t, "s3cret", cfg.Password)
+	assert.Equal(t, "test", cfg.DB)
+	assert.Equal(t, 3306, cfg.Pool.MaxOpen)
+	assert.Equal(t, "${shared.host}", c.Koanf().String("literal"))
+	assert.Equal(t, []string{"10.0.0.1", "plain"}, c.Koanf().Strings("list"))
+}
+
+func TestConfig_InterpolateErrors(t *testing.T) {
+	c, err := NewFromYaml([]byte("a: ${b}\nb: ${c}\nc: ${a}\n"))
+	assert.NoError(t, err)
+	_, err = c.interpolate(c.Koanf(), nil, nil)
+	assert.EqualError(t, err, `config key "a": circular reference a -> b -> c -> a`)
+
+	c, err = NewFromYaml([]byte("db:\n  dsn: ${db.missing}\n"))
+	assert.NoError(t, err)
+	_, err = c.interpolate(c.Koanf(), nil, nil)
+	assert.EqualError(t, err, `config key "db.dsn": unresolved ${db.missing}`)
+
+	c, err = NewFromYaml([]byte("a: ${ENV:NOT_EXISTS_ENV_FOR_TEST}\n"))
+	assert.NoError(t, err)
+	_, err = c.interpolate(c.Koanf(), nil, nil)
+	assert.ErrorContains(t, err, `config key "a": unresolved ${ENV:NOT_EXISTS_ENV_FOR_TEST}`)
+
+	c, err = NewFromYaml([]byte("a: ${custom:x}\n"))
+	assert.NoError(t, err)
+	WithResolver("custom", func(ref string) (string, error) { return "custom-" + ref, nil })(c)
+	sensitive, err := c.interpolate(c.Koanf(), nil, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "custom-x", c.Koanf().String("a"))
+	assert.True(t, sensitive["a"])
+}
+
+func TestConfig_InterpolateEnvLiteral(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	assert.NoError(t, os.WriteFile(file, []byte("db:\n  host: a\n  url: ${db.host}:3306\n"), 0644))
+	t.Setenv("APP_FILE", file)
+	t.Setenv("FOO", "${bar}")
+	t.Setenv("DB_NAME", "${db.host}")
+
+	// 环境变量中无法解析的 ${...} 保留原值, 可以解析的照常替换
+	c := New()
+	assert.NoError(t, c.ReadConfig())
+	assert.Equal(t, "${bar}", c.Koanf().String("foo"))
+	assert.Equal(t, "a", c.Koanf().String("db.name"))
+	assert.Equal(t, "a:3306", c.Koanf().String("db.url"))
+	assert.NoError(t, c.Reload())
+	assert.Equal(t, "${bar}", c.Koanf().String("foo"))
+}
@@ -2,6 +2,8 @@ package config
 
 import (
 	"encoding/json"
+	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/neura-flow/common/util"
@@ -31,13 +33,24 @@ func newPropertiesToMap(keyDelimiter string) *propertiesToMap {
 	}
 }
 
+// Do 把 props 展开为嵌套结构. 按层级从浅到深、相同层级按名称的顺序合并, 结果与 map 的遍历顺序无关,
+// 冲突时(如 a=1 和 a.b=2)层级深的 key 覆盖层级浅的
 func (p *propertiesToMap) Do(props map[string]any) map[string]any {
+	keys := make([][]string, 0, len(props))
+	for k := range props {
+		keys = append(keys, strings.Split(k, p.keyDelimiter))
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if len(keys[i]) != len(keys[j]) {
+			return len(keys[i]) < len(keys[j])
+		}
+		return strings.Join(keys[i], p.keyDelimiter) < strings.Join(keys[j], p.keyDelimiter)
+	})
 	output := make(map[string]any)
-	for k, v := range props {
-		path := strings.Split(k, p.keyDelimiter)
+	for _, path := range keys {
 		lastKey := path[len(path)-1]
 		deepestMap := p.deepSearch(output, path[0:len(path)-1])
-		deepestMap[lastKey] = v
+		deepestMap[lastKey] = props[strings.Join(path, p.keyDelimiter)]
 	}
 	return output
 }
@@ -63,3 +76,52 @@ func (p *propertiesToMap) deepSearch(m map[string]any, path []string) map[string
 	}
 	return m
 }
+
+type propertiesParser struct{}
+
+// PropertiesParser 解析 key=value (或 key: value) 格式的 .properties 文件, key 中的 '.' 展开为嵌套结构,
+// '#' 或 '!' 开头的行为注释
+func PropertiesParser() *propertiesParser {
+	return &propertiesParser{}
+}
+
+func (p *propertiesParser) Unmarshal(b []byte) (map[string]interface{}, error) {
+	kvm := make(map[string]interface{})
+	for i, line := range strings.Split(string(b), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "!") {
+			continue
+		}
+		idx := strings.IndexAny(line, "=:")
+		if idx <= 0 {
+			return nil, fmt.Errorf("invalid properties at line %d: %s", i+1, line)
+		}
+		kvm[strings.TrimSpace(line[:idx])] = strings.TrimSpace(line[idx+1:])
+	}
+	return newPropertiesToMap(".").Do(kvm), nil
+}
+
+func (p *propertiesParser) Marshal(o map[string]interface{}) ([]byte, error) {
+	var buf strings.Builder
+	keys := make([]string, 0)
+	flat := make(map[string]interface{})
+	flatten("", o, flat)
+	for k := range flat {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		buf.WriteString(fmt.Sprintf("%s=%v\n", k, flat[k]))
+	}
+	return []byte(buf.String()), nil
+}
+
+func flatten(prefix string, m map[string]interface{}, out map[string]interface{}) {
+	for k, v := range m {
+		if sub, ok := v.(map[string]interface{}); ok {
+			flatten(join(prefix, k), sub, out)
+		} else {
+			out[join(prefix, k)] = v
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package config
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/knadh/koanf/v2"
+)
+
+// 内置 Source 的优先级, 优先级低的先加载, 高的覆盖低的
+const (
+	PriorityEnv    = 100
+	PriorityFile   = 200
+	PriorityDir    = 250
+	PriorityHTTP   = 280
+	PriorityConsul = 300
+	PriorityEtcd   = 320
+	PriorityZk     = 340
+	PriorityVault  = 400
+)
+
+// SourceFactory 根据已加载的配置 k (如地址、路径等) 创建写入 target 的 Source
+type SourceFactory func(k, target *koanf.Koanf) Source
+
+type SourceOption func(r *registration)
+
+// WithEnabledKey 仅当配置项 key 为 true 时才加载该 Source
+func WithEnabledKey(key string) SourceOption {
+	return func(r *registration) {
+		r.enabledKey = key
+	}
+}
+
+type registration struct {
+	name       string
+	priority   int
+	factory    SourceFactory
+	enabledKey string
+}
+
+func (r *registration) enabled(k *koanf.Koanf) bool {
+	return r.enabledKey == "" || k.Bool(r.enabledKey)
+}
+
+type registry struct {
+	mu    sync.RWMutex
+	items map[string]*registration
+}
+
+func newRegistry() *registry {
+	return &registry{items: make(map[string]*registration)}
+}
+
+func (r *registry) register(name string, priority int, factory SourceFactory, opts ...SourceOption) {
+	item := &registration{
+		name:     name,
+		priority: priority,
+		factory:  factory,
+	}
+	for _, opt := range opts {
+		opt(item)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.items[name] = item
+}
+
+func (r *registry) unregister(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.items, name)
+}
+
+// sorted 按优先级从低到高排序, 相同优先级按名称排序以保证加载顺序稳定
+func (r *registry) sorted() []*registration {
+	r.mu.RLock()
+	list := make([]*registration, 0, len(r.items))
+	for _, item := range r.items {
+		list = append(list, item)
+	}
+	r.mu.RUnlock()
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].priority != list[j].priority {
+			return list[i].priority < list[j].priority
+		}
+		return list[i].name < list[j].name
+	})
+	return list
+}
+
+func (r *registry) clone() *registry {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	c := newRegistry()
+	for name, item := range r.items {
+		c.items[name] = item
+	}
+	return c
+}
+
+var defaultRegistry = newRegistry()
+
+func init() {
+	RegisterSource(SourceEnv, PriorityEnv, func(k, target *koanf.Koanf) Source {
+		return NewEnvSource(target)
+	})
+	RegisterSource(SourceFile, PriorityFile, nil)
+	RegisterSource(SourceDir, PriorityDir, func(k, target *koanf.Koanf) Source {
+		return NewDirSource(target, kstring(k, DirPath, DefaultDirPath))
+	}, WithEnabledKey(DirEnabled))
+	RegisterSource(SourceHTTP, PriorityHTTP, func(k, target *koanf.Koanf) Source {
+		return NewHTTPSource(target, k.String(HTTPUrl), k.String(HTTPFormat), k.Duration(HTTPTimeout))
+	}, WithEnabledKey(HTTPEnabled))
+	RegisterSource(SourceConsul, PriorityConsul, nil, WithEnabledKey(ConsulEnabled))
+	RegisterSource(SourceEtcd, PriorityEtcd, func(k, target *koanf.Koanf) Source {
+		return NewEtcdSource(target, kstring(k, EtcdEndpoints, DefaultEtcdEndpoints), k.String(EtcdToken),
+			kstring(k, EtcdPrefix, DefaultEtcdPrefix))
+	}, WithEnabledKey(EtcdEnabled))
+	RegisterSource(SourceZk, PriorityZk, func(k, target *koanf.Koanf) Source {
+		return NewZkSource(target, k.String(ZkServers), kstring(k, ZkPath, DefaultZkPath))
+	}, WithEnabledKey(ZkEnabled))
+	RegisterSource(SourceVault, PriorityVault, nil, WithEnabledKey(VaultEnabled))
+}
+
+// RegisterSource 注册全局 Source, 对之后创建的 Config 生效, 同名 Source 会被替换(可用于调整内置 Source 的优先级).
+// factory 为 nil 时使用内置的 file/consul/vault 实现
+func RegisterSource(name string, priority int, factory SourceFactory, opts ...SourceOption) {
+	defaultRegistry.register(name, priority, factory, opts...)
+}
+
+// UnregisterSource 移除全局 Source
+func UnregisterSource(name string) {
+	defaultRegistry.unregister(name)
+}
+
+// WithSource 为当前 Config 注册 Source, 同名 Source 会被替换
+func WithSource(name string, priority int, factory SourceFactory, opts ...SourceOption) Option {
+	return func(c *Config) {
+		c.registry.register(name, priority, factory, opts...)
+	}
+}
+
+// WithoutSource 为当前 Config 移除 Source
+func WithoutSource(name string) Option {
+	return func(c *Config) {
+		c.registry.unregister(name)
+	}
+}
+
+// Register 为当前 Config 注册 Source, 在下一次 ReadConfig/Reload 时生效
+func (c *Config) Register(name string, priority int, factory SourceFactory, opts ...SourceOption) {
+	c.registry.register(name, priority, factory, opts...)
+}
+
+// sourceDef 已注册的 Source, new 在未开启时返回 nil
+type sourceDef struct {
+	name string
+	new  SourceFactory
+}
+
+// sourceDefs 按优先级返回已注册的 Source, 是否开启由前面已加载的配置决定
+func (c *Config) sourceDefs() []sourceDef {
+	list := make([]sourceDef, 0)
+	for _, r := range c.registry.sorted() {
+		r, factory := r, r.factory
+		if factory == nil {
+			factory = c.builtinFactory(r.name)
+		}
+		if factory == nil {
+			continue
+		}
+		list = append(list, sourceDef{name: r.name, new: func(k, target *koanf.Koanf) Source {
+			if !r.enabled(k) {
+				return nil
+			}
+			return factory(k, target)
+		}})
+	}
+	return list
+}
+
+// builtinFactory 内置的依赖 Config 自身设置(如 cfgVar)的 Source
+func (c *Config) builtinFactory(name string) SourceFactory {
+	switch name {
+	case SourceFile:
+		return func(k, target *koanf.Koanf) Source {
+			return NewFileSource(target, kstring(k, c.configFileVar(), DefaultCfgFile), profiles(k)...)
+		}
+	case SourceConsul:
+		return c.newConsulSource
+	case SourceVault:
+		return c.newVaultSource
+	}
+	return nil
+}
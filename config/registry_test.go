@@ -0,0 +1,89 @@
+package config
+
+import (
+	"encoding/base64"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/knadh/koanf/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+type mapSource struct {
+	k    *koanf.Koanf
+	data map[string]interface{}
+}
+
+func (s *mapSource) Load() error {
+	return loadFlat(s.k, s.data)
+}
+
+func newMapSource(data map[string]interface{}) SourceFactory {
+	return func(k, target *koanf.Koanf) Source {
+		return &mapSource{k: target, data: data}
+	}
+}
+
+func TestConfig_RegisterPriority(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	assert.NoError(t, os.WriteFile(file, []byte("a: file\nb: file\nc: file\n"), 0644))
+	t.Setenv("APP_FILE", file)
+
+	c := New(
+		WithSource("low", PriorityFile-1, newMapSource(map[string]interface{}{"a": "low", "d": "low"})),
+		WithSource("high", PriorityFile+1, newMapSource(map[string]interface{}{"b": "high"})),
+		WithSource("off", PriorityFile+2, newMapSource(map[string]interface{}{"c": "off"}), WithEnabledKey("off.enabled")),
+	)
+	assert.NoError(t, c.ReadConfig())
+	k := c.Koanf()
+	assert.Equal(t, "file", k.String("a"))
+	assert.Equal(t, "high", k.String("b"))
+	assert.Equal(t, "file", k.String("c"))
+	assert.Equal(t, "low", k.String("d"))
+	assert.Equal(t, "high", c.Explain("b")[0].Source)
+}
+
+func TestDirSource(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "10-db.yaml"), []byte("db:\n  host: a\n  port: 1\n"), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "20-db.json"), []byte(`{"db":{"host":"b"}}`), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "README.md"), []byte("ignored"), 0644))
+	k := koanf.New(".")
+	assert.NoError(t, NewDirSource(k, dir).Load())
+	assert.Equal(t, "b", k.String("db.host"))
+	assert.Equal(t, 1, k.Int("db.port"))
+	assert.NoError(t, NewDirSource(k, filepath.Join(dir, "missing")).Load())
+}
+
+func TestHTTPSource(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"db":{"host":"remote"}}`))
+	}))
+	defer srv.Close()
+	k := koanf.New(".")
+	assert.NoError(t, NewHTTPSource(k, srv.URL+"/config", "", 0).Load())
+	assert.Equal(t, "remote", k.String("db.host"))
+}
+
+func TestEtcdSource(t *testing.T) {
+	enc := base64.StdEncoding.EncodeToString
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v3/kv/range", r.URL.Path)
+		body, _ := io.ReadAll(r.Body)
+		assert.JSONEq(t, `{"key":"`+enc([]byte("config/"))+`","range_end":"`+enc([]byte("config0"))+`"}`, string(body))
+		_, _ = w.Write([]byte(`{"kvs":[
+			{"key":"` + enc([]byte("config/db/host")) + `","value":"` + enc([]byte("etcd")) + `"},
+			{"key":"` + enc([]byte("config/db/port")) + `","value":"` + enc([]byte("3306")) + `"}
+		]}`))
+	}))
+	defer srv.Close()
+	k := koanf.New(".")
+	assert.NoError(t, NewEtcdSource(k, srv.URL, "", "config").Load())
+	assert.Equal(t, "etcd", k.String("db.host"))
+	assert.Equal(t, 3306, k.Int("db.port"))
+}
@@ -0,0 +1,275 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/knadh/koanf/v2"
+	"github.com/neura-flow/common/cipher"
+)
+
+// consul/vault 的超时、重试以及离线缓存配置, 例如:
+//
+//	consul:
+//	  timeout: 5s    # 单次请求超时, 默认 10s
+//	  retries: 3     # 失败后的重试次数, 默认 2
+//	  backoff: 500ms # 首次重试的等待时间, 之后每次翻倍, 默认 1s
+//	vault:
+//	  kv: 2          # kv 引擎版本, 默认 1
+//	config:
+//	  cache:
+//	    enabled: true
+//	    dir: ./configs/.cache
+//	    key: ${env:CONFIG_CACHE_KEY} # 加密缓存文件的口令
+//
+// 开启缓存后, 每次成功加载都会把结果加密写入缓存文件, 重试后仍无法访问时使用缓存中的配置.
+// consul_token、vault_token 以及 config.cache.key 支持 ENC(...) 和 env/file 占位符
+const (
+	ConsulTimeout   = "consul.timeout"
+	ConsulRetries   = "consul.retries"
+	ConsulBackoff   = "consul.backoff"
+	VaultTimeout    = "vault.timeout"
+	VaultRetries    = "vault.retries"
+	VaultBackoff    = "vault.backoff"
+	VaultKV         = "vault.kv"
+	CacheEnabled    = "config.cache.enabled"
+	CacheDir        = "config.cache.dir"
+	CacheKey        = "config.cache.key"
+	DefaultCacheDir = "./configs/.cache"
+
+	DefaultRemoteTimeout = 10 * time.Second
+	DefaultRemoteRetries = 2
+	DefaultRemoteBackoff = time.Second
+	maxRemoteBackoff     = 30 * time.Second
+)
+
+// RemoteOption consul/vault Source 的选项
+type RemoteOption func(o *remoteOptions)
+
+type remoteOptions struct {
+	timeout   time.Duration
+	retries   int
+	backoff   time.Duration
+	kvVersion int
+	cache     *snapshotCache
+	onError   func(err error)
+}
+
+func newRemoteOptions(opts []RemoteOption) *remoteOptions {
+	o := &remoteOptions{
+		timeout:   DefaultRemoteTimeout,
+		retries:   DefaultRemoteRetries,
+		backoff:   DefaultRemoteBackoff,
+		kvVersion: 1,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// WithRemoteTimeout 设置单次请求的超时时间
+func WithRemoteTimeout(d time.Duration) RemoteOption {
+	return func(o *remoteOptions) {
+		if d > 0 {
+			o.timeout = d
+		}
+	}
+}
+
+// WithRemoteRetry 设置失败后的重试次数以及首次重试的等待时间, 之后每次等待时间翻倍
+func WithRemoteRetry(retries int, backoff time.Duration) RemoteOption {
+	return func(o *remoteOptions) {
+		if retries >= 0 {
+			o.retries = retries
+		}
+		if backoff > 0 {
+			o.backoff = backoff
+		}
+	}
+}
+
+// WithVaultKV 设置 vault kv 引擎版本, 1 或 2
+func WithVaultKV(version int) RemoteOption {
+	return func(o *remoteOptions) {
+		o.kvVersion = version
+	}
+}
+
+// WithRemoteCache 开启加密的离线缓存, 缓存文件位于 dir 下, 使用 key 加密
+func WithRemoteCache(dir, key string) RemoteOption {
+	return func(o *remoteOptions) {
+		o.cache = &snapshotCache{dir: dir, key: key}
+	}
+}
+
+// WithRemoteErrorHandler 设置不影响加载结果的错误(如使用了离线缓存、写缓存失败)的处理函数
+func WithRemoteErrorHandler(h func(err error)) RemoteOption {
+	return func(o *remoteOptions) {
+		o.onError = h
+	}
+}
+
+// remoteOptions 根据已加载的配置生成 consul/vault Source 的选项
+func (c *Config) remoteOptions(k *koanf.Koanf, timeoutKey, retriesKey, backoffKey string) []RemoteOption {
+	retries := -1
+	if k.Exists(retriesKey) {
+		retries = k.Int(retriesKey)
+	}
+	opts := []RemoteOption{
+		WithRemoteTimeout(k.Duration(timeoutKey)),
+		WithRemoteRetry(retries, k.Duration(backoffKey)),
+		WithRemoteErrorHandler(c.handleError),
+	}
+	if k.Bool(CacheEnabled) {
+		dir := k.String(CacheDir)
+		if dir == "" {
+			dir = DefaultCacheDir
+		}
+		opts = append(opts, WithRemoteCache(dir, c.bootstrapString(k, CacheKey)))
+	}
+	return opts
+}
+
+// bootstrapString 读取构建 consul/vault Source 时使用的配置项(token、缓存口令等).
+// Source 在 Reload 解密和解析占位符之前创建, 因此单独解密 ENC(...) 并解析其中的占位符,
+// 失败时交给错误处理函数并返回空字符串
+func (c *Config) bootstrapString(k *koanf.Koanf, key string) string {
+	v := k.String(key)
+	if IsEncrypted(v) {
+		r, err := c.decrypter(k)
+		if err == nil {
+			v, err = Decrypt(r, v)
+		}
+		if err != nil {
+			c.handleError(fmt.Errorf("config key %q: failed to decrypt: %w", key, err))
+			return ""
+		}
+	}
+	if c.noInterpolate || !strings.Contains(v, "${") {
+		return v
+	}
+	it := &interpolator{
+		k:         k,
+		resolvers: c.localResolvers(),
+		resolved:  make(map[string]interface{}),
+	}
+	resolved, err := it.expand(v, []string{key})
+	if err != nil {
+		c.handleError(err)
+		return ""
+	}
+	return fmt.Sprint(resolved)
+}
+
+// fetch 带重试地读取 consul/vault 中的配置, 成功后写入缓存, 重试后仍失败时使用缓存
+func (o *remoteOptions) fetch(name string, read func(ctx context.Context) (map[string]string, error)) (map[string]string, error) {
+	var data map[string]string
+	err := o.retry(func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), o.timeout)
+		defer cancel()
+		var err error
+		data, err = read(ctx)
+		return err
+	})
+	if o.cache == nil {
+		return data, err
+	}
+	if err == nil {
+		if cacheErr := o.cache.save(name, data); cacheErr != nil {
+			o.handleError(fmt.Errorf("failed to save %s config cache: %w", name, cacheErr))
+		}
+		return data, nil
+	}
+	cached, cacheErr := o.cache.load(name)
+	if cacheErr != nil {
+		return nil, fmt.Errorf("%w, cache: %v", err, cacheErr)
+	}
+	o.handleError(fmt.Errorf("%s is unavailable, using cached config: %w", name, err))
+	return cached, nil
+}
+
+func (o *remoteOptions) handleError(err error) {
+	if o.onError != nil {
+		o.onError(err)
+	}
+}
+
+func (o *remoteOptions) retry(fn func() error) error {
+	backoff := o.backoff
+	var err error
+	for i := 0; ; i++ {
+		if err = fn(); err == nil || i >= o.retries {
+			return err
+		}
+		time.Sleep(backoff)
+		if backoff *= 2; backoff > maxRemoteBackoff {
+			backoff = maxRemoteBackoff
+		}
+	}
+}
+
+// snapshotCache 使用 AES 加密保存最近一次成功加载的配置
+type snapshotCache struct {
+	dir string
+	key string
+}
+
+func (c *snapshotCache) path(name string) string {
+	return filepath.Join(c.dir, name+".cache")
+}
+
+func (c *snapshotCache) aes() (*cipher.AES, error) {
+	if c.key == "" {
+		return nil, errors.New("config cache key is required")
+	}
+	return cipher.NewAES(c.key)
+}
+
+func (c *snapshotCache) save(name string, data map[string]string) error {
+	a, err := c.aes()
+	if err != nil {
+		return err
+	}
+	buf, err := jsoniter.Marshal(data)
+	if err != nil {
+		return err
+	}
+	if buf, err = a.Encrypt(buf); err != nil {
+		return err
+	}
+	if err = os.MkdirAll(c.dir, 0700); err != nil {
+		return err
+	}
+	// 先写临时文件再 rename, 避免进程退出时留下不完整的缓存
+	tmp := c.path(name) + ".tmp"
+	if err = os.WriteFile(tmp, buf, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, c.path(name))
+}
+
+func (c *snapshotCache) load(name string) (map[string]string, error) {
+	a, err := c.aes()
+	if err != nil {
+		return nil, err
+	}
+	buf, err := os.ReadFile(c.path(name))
+	if err != nil {
+		return nil, err
+	}
+	if buf, err = a.Decrypt(buf); err != nil {
+		return nil, err
+	}
+	data := make(map[string]string)
+	if err = jsoniter.Unmarshal(buf, &data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
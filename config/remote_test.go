@@ -0,0 +1,189 @@
+package config
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/knadh/koanf/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// newConsulServer 模拟 consul kv 接口, 前 failures 次请求返回错误
+func newConsulServer(t *testing.T, failures int32, kvs map[string]string) (*httptest.Server, *int32) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if n := atomic.AddInt32(&calls, 1); n <= failures || failures < 0 {
+			http.Error(w, "unavailable", http.StatusInternalServerError)
+			return
+		}
+		list := make([]map[string]interface{}, 0)
+		prefix := r.URL.Path[len("/v1/kv/"):]
+		for k, v := range kvs {
+			if len(k) > len(prefix) && k[:len(prefix)] == prefix {
+				list = append(list, map[string]interface{}{"Key": k, "Value": base64.StdEncoding.EncodeToString([]byte(v))})
+			}
+		}
+		w.Header().Set("X-Consul-Index", "1")
+		_ = jsoniter.NewEncoder(w).Encode(list)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &calls
+}
+
+func TestConsulSource_Retry(t *testing.T) {
+	srv, calls := newConsulServer(t, 2, map[string]string{
+		"config/application/db.host": "a",
+		"config/app/db.port":         "3306",
+	})
+	k := koanf.New(".")
+	s := NewConsulSource(k, srv.URL, "", "config", "app", true, WithRemoteRetry(2, time.Millisecond))
+	assert.NoError(t, s.Load())
+	assert.Equal(t, "a", k.String("db.host"))
+	assert.Equal(t, 3306, k.Int("db.port"))
+	// 两个前缀各请求一次, 前两次失败
+	assert.Equal(t, int32(4), atomic.LoadInt32(calls))
+
+	srv, _ = newConsulServer(t, 5, nil)
+	s = NewConsulSource(koanf.New("."), srv.URL, "", "config", "app", true, WithRemoteRetry(1, time.Millisecond))
+	assert.Error(t, s.Load())
+}
+
+func TestConsulSource_Cache(t *testing.T) {
+	dir := t.TempDir()
+	srv, _ := newConsulServer(t, 0, map[string]string{"config/application/db.host": "a"})
+	k := koanf.New(".")
+	assert.NoError(t, NewConsulSource(k, srv.URL, "", "config", "app", true, WithRemoteCache(dir, "key")).Load())
+	assert.Equal(t, "a", k.String("db.host"))
+
+	// 后端不可用时使用缓存
+	srv, _ = newConsulServer(t, -1, nil)
+	var fallback error
+	k = koanf.New(".")
+	s := NewConsulSource(k, srv.URL, "", "config", "app", true,
+		WithRemoteRetry(1, time.Millisecond), WithRemoteCache(dir, "key"),
+		WithRemoteErrorHandler(func(err error) { fallback = err }))
+	assert.NoError(t, s.Load())
+	assert.Equal(t, "a", k.String("db.host"))
+	assert.Error(t, fallback)
+
+	// 口令错误时无法解密缓存
+	s = NewConsulSource(koanf.New("."), srv.URL, "", "config", "app", true,
+		WithRemoteRetry(0, time.Millisecond), WithRemoteCache(dir, "other"))
+	assert.Error(t, s.Load())
+}
+
+func TestConsulSource_Timeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}))
+	defer srv.Close()
+	s := NewConsulSource(koanf.New("."), srv.URL, "", "config", "app", true,
+		WithRemoteTimeout(50*time.Millisecond), WithRemoteRetry(0, time.Millisecond))
+	start := time.Now()
+	assert.Error(t, s.Load())
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+}
+
+func TestVaultSource_KV(t *testing.T) {
+	var paths []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		switch r.URL.Path {
+		case "/v1/kv/data/application":
+			_, _ = fmt.Fprint(w, `{"data":{"data":{"db.password":"p1"},"metadata":{"version":1}}}`)
+		case "/v1/kv/data/app":
+			_, _ = fmt.Fprint(w, `{"data":{"data":{"redis.password":"p2"},"metadata":{"version":3}}}`)
+		case "/v1/kv/application":
+			_, _ = fmt.Fprint(w, `{"data":{"db.password":"v1"}}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = fmt.Fprint(w, `{"errors":[]}`)
+		}
+	}))
+	defer srv.Close()
+
+	k := koanf.New(".")
+	assert.NoError(t, NewVaultSource(k, srv.URL, "token", "kv", "app", true, WithVaultKV(2)).Load())
+	assert.Equal(t, "p1", k.String("db.password"))
+	assert.Equal(t, "p2", k.String("redis.password"))
+	assert.Equal(t, []string{"/v1/kv/data/application", "/v1/kv/data/app"}, paths)
+
+	paths = nil
+	k = koanf.New(".")
+	assert.NoError(t, NewVaultSource(k, srv.URL, "token", "kv/", "app", true).Load())
+	assert.Equal(t, "v1", k.String("db.password"))
+	assert.Equal(t, []string{"/v1/kv/application", "/v1/kv/app"}, paths)
+}
+
+func TestConfig_RemoteBootstrapPlaceholders(t *testing.T) {
+	var down int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(&down) == 1 {
+			http.Error(w, "unavailable", http.StatusInternalServerError)
+			return
+		}
+		if r.Header.Get("X-Consul-Token") != "consul-secret" {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		list := make([]map[string]interface{}, 0)
+		if r.URL.Path == "/v1/kv/config/application/" {
+			list = append(list, map[string]interface{}{"Key": "config/application/db.host", "Value": base64.StdEncoding.EncodeToString([]byte("a"))})
+		}
+		w.Header().Set("X-Consul-Index", "1")
+		_ = jsoniter.NewEncoder(w).Encode(list)
+	}))
+	defer srv.Close()
+
+	// 远程缓存文件名包含 app.file, 使用相对路径
+	dir := t.TempDir()
+	wd, err := os.Getwd()
+	assert.NoError(t, err)
+	assert.NoError(t, os.Chdir(dir))
+	t.Cleanup(func() { _ = os.Chdir(wd) })
+	assert.NoError(t, os.WriteFile("config.yaml", []byte(fmt.Sprintf(`consul:
+  enabled: true
+  address: %s
+  retries: 0
+consul_token: ${env:CONSUL_TOKEN}
+config:
+  cache:
+    enabled: true
+    dir: %s
+    key: ${env:CONFIG_CACHE_KEY}
+`, srv.URL, filepath.Join(dir, "cache"))), 0644))
+	t.Setenv("APP_FILE", "config.yaml")
+	t.Setenv("CONSUL_TOKEN", "consul-secret")
+	t.Setenv("CONFIG_CACHE_KEY", "cache-secret")
+
+	var errs []error
+	c := New(WithErrorHandler(func(err error) { errs = append(errs, err) }))
+	assert.NoError(t, c.ReadConfig())
+	assert.Equal(t, "a", c.Koanf().String("db.host"))
+	assert.Empty(t, errs)
+
+	// 缓存使用解析后的口令加密, 而不是字面量 ${env:CONFIG_CACHE_KEY}
+	for _, o := range c.Effective("") {
+		switch o.Key {
+		case CacheKey, ConsulToken:
+			assert.Equal(t, RedactedValue, o.Value, o.Key)
+		}
+	}
+	opts := newRemoteOptions(c.remoteOptions(c.Koanf(), ConsulTimeout, ConsulRetries, ConsulBackoff))
+	assert.Equal(t, "cache-secret", opts.cache.key)
+	atomic.StoreInt32(&down, 1)
+	assert.NoError(t, c.Reload())
+	assert.Equal(t, "a", c.Koanf().String("db.host"))
+	assert.Len(t, errs, 1)
+}
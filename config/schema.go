@@ -0,0 +1,339 @@
+package config
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/neura-flow/common/util"
+)
+
+const (
+	TagDesc = "desc"
+
+	SchemaDraft = "http://json-schema.org/draft-07/schema#"
+)
+
+var textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+
+// Enum 由类型自身提供可选值, 如 log.Level
+type Enum interface {
+	Enum() []string
+}
+
+// Schema JSON Schema (draft-07) 的子集
+type Schema struct {
+	Schema               string             `json:"$schema,omitempty"`
+	Title                string             `json:"title,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Default              interface{}        `json:"default,omitempty"`
+	Enum                 []interface{}      `json:"enum,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+
+	order []string // 属性按字段声明顺序排列, 用于生成文档
+}
+
+// JSONSchema 根据结构体的 json/desc/default/validate/regex tag 生成 JSON Schema, 可用于编辑器校验 yaml 配置.
+// v 中的非零值字段作为默认值, 例如 JSONSchema(log.DefaultConfig); json 名称包含 '.' 时(如 ArgsDef)展开为嵌套对象
+func JSONSchema(v interface{}) *Schema {
+	g := &schemaGenerator{visiting: make(map[reflect.Type]bool)}
+	s := g.generate(reflect.ValueOf(v))
+	s.Schema = SchemaDraft
+	return s
+}
+
+// MarshalSchema 返回格式化后的 JSON Schema
+func MarshalSchema(v interface{}) ([]byte, error) {
+	return jsoniter.MarshalIndent(JSONSchema(v), "", "  ")
+}
+
+// Markdown 生成配置项说明的 markdown 表格, 每个配置项一行, prefix 为配置项在整个配置中的路径, 例如:
+//
+//	| key | type | default | description |
+//	| --- | --- | --- | --- |
+//	| log.level | string | info | 日志级别，默认 info 级别 |
+func Markdown(prefix string, v interface{}) string {
+	var buf strings.Builder
+	buf.WriteString(util.ToMarkDown([]string{"key", "type", "default", "description"}, true))
+	writeMarkdown(&buf, prefix, JSONSchema(v))
+	return buf.String()
+}
+
+func writeMarkdown(buf *strings.Builder, key string, s *Schema) {
+	switch {
+	case len(s.Properties) > 0:
+		for _, name := range s.order {
+			writeMarkdown(buf, join(key, name), s.Properties[name])
+		}
+		return
+	case s.Type == "array" && s.Items != nil && len(s.Items.Properties) > 0:
+		writeMarkdown(buf, key+"[]", s.Items)
+		return
+	case s.Type == "object" && s.AdditionalProperties != nil && len(s.AdditionalProperties.Properties) > 0:
+		writeMarkdown(buf, join(key, "*"), s.AdditionalProperties)
+		return
+	}
+	typ := s.Type
+	if s.Type == "array" && s.Items != nil && s.Items.Type != "" {
+		typ = "[]" + s.Items.Type
+	}
+	desc := s.Description
+	if len(s.Enum) > 0 {
+		desc = strings.TrimSpace(fmt.Sprintf("%s (%s)", desc, strings.Trim(fmt.Sprint(s.Enum), "[]")))
+	}
+	def := ""
+	if s.Default != nil {
+		def = fmt.Sprint(s.Default)
+		if _, ok := s.Default.(string); !ok {
+			def = util.ToJson(s.Default)
+		}
+	}
+	buf.WriteString(util.ToMarkDown([]string{key, typ, escapeMarkdown(def), escapeMarkdown(desc)}, false))
+}
+
+func escapeMarkdown(s string) string {
+	return strings.NewReplacer("|", "\\|", "\n", " ").Replace(s)
+}
+
+type schemaGenerator struct {
+	visiting map[reflect.Type]bool // 用于避免递归类型无限展开
+}
+
+// generate 生成 v 的 schema, v 可能是无效值(仅用于类型)
+func (g *schemaGenerator) generate(v reflect.Value) *Schema {
+	if !v.IsValid() {
+		return &Schema{}
+	}
+	t := v.Type()
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+		if v.IsValid() && !v.IsNil() {
+			v = v.Elem()
+		} else {
+			v = reflect.Value{}
+		}
+	}
+	if !v.IsValid() {
+		v = reflect.Zero(t)
+	}
+	s := g.typeSchema(t)
+	if s.Type == "object" && t.Kind() == reflect.Struct && !g.visiting[t] {
+		g.visiting[t] = true
+		g.properties(s, v)
+		delete(g.visiting, t)
+	}
+	return s
+}
+
+// typeSchema 返回类型对应的 schema, 不包含结构体的属性
+func (g *schemaGenerator) typeSchema(t reflect.Type) *Schema {
+	s := &Schema{}
+	if e, ok := reflect.Zero(t).Interface().(Enum); ok {
+		for _, item := range e.Enum() {
+			s.Enum = append(s.Enum, item)
+		}
+	}
+	if t == durationType {
+		s.Type = "string"
+		s.Pattern = `^(\d+(\.\d+)?(ns|us|µs|ms|s|m|h))+$`
+		return s
+	}
+	if t.Kind() == reflect.Struct && (t.Implements(textMarshalerType) || reflect.PtrTo(t).Implements(textMarshalerType)) {
+		s.Type = "string"
+		return s
+	}
+	switch t.Kind() {
+	case reflect.String:
+		s.Type = "string"
+	case reflect.Bool:
+		s.Type = "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		s.Type = "integer"
+	case reflect.Float32, reflect.Float64:
+		s.Type = "number"
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			s.Type = "string"
+			break
+		}
+		s.Type = "array"
+		s.Items = g.generate(reflect.Zero(t.Elem()))
+	case reflect.Map:
+		s.Type = "object"
+		s.AdditionalProperties = g.generate(reflect.Zero(t.Elem()))
+	case reflect.Struct:
+		s.Type = "object"
+	}
+	return s
+}
+
+func (g *schemaGenerator) properties(s *Schema, v reflect.Value) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		ft := t.Field(i)
+		if ft.PkgPath != "" {
+			continue
+		}
+		name := strings.Split(ft.Tag.Get("json"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		fv := v.Field(i)
+		fs := g.generate(fv)
+		if ft.Anonymous && name == "" && fs.Type == "object" && fs.Properties != nil {
+			// 匿名字段的属性合并到当前结构体
+			for _, n := range fs.order {
+				s.addProperty(n, fs.Properties[n])
+			}
+			s.Required = append(s.Required, fs.Required...)
+			continue
+		}
+		if name == "" {
+			name = ft.Name
+		}
+		fs.Description = ft.Tag.Get(TagDesc)
+		applyTags(fs, ft, fv)
+		// json 名称中的 '.' 表示嵌套, 如 app.name
+		parent, parts := s, strings.Split(name, ".")
+		for _, part := range parts[:len(parts)-1] {
+			child, ok := parent.Properties[part]
+			if !ok {
+				child = &Schema{Type: "object"}
+				parent.addProperty(part, child)
+			}
+			parent = child
+		}
+		leaf := parts[len(parts)-1]
+		parent.addProperty(leaf, fs)
+		if hasRule(ft.Tag, "required") {
+			parent.Required = append(parent.Required, leaf)
+		}
+	}
+}
+
+func (s *Schema) addProperty(name string, p *Schema) {
+	if s.Properties == nil {
+		s.Properties = make(map[string]*Schema)
+	}
+	if _, ok := s.Properties[name]; !ok {
+		s.order = append(s.order, name)
+	}
+	s.Properties[name] = p
+}
+
+// applyTags 把 default/validate/regex tag 以及字段当前值(非零时作为默认值)写入 schema
+func applyTags(s *Schema, ft reflect.StructField, fv reflect.Value) {
+	if fv.IsValid() && !fv.IsZero() && s.Properties == nil {
+		s.Default = schemaValue(fv)
+	} else if def, ok := ft.Tag.Lookup(TagDefault); ok {
+		dv := reflect.New(ft.Type).Elem()
+		if err := setDefault(dv, def); err == nil {
+			s.Default = schemaValue(dv)
+		} else {
+			s.Default = def
+		}
+	}
+	if pattern, ok := ft.Tag.Lookup(TagRegex); ok {
+		s.Pattern = pattern
+	}
+	t := ft.Type
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	for _, rule := range strings.Split(ft.Tag.Get(TagValidate), ",") {
+		name, arg, _ := strings.Cut(strings.TrimSpace(rule), "=")
+		switch name {
+		case "oneof":
+			s.Enum = nil
+			for _, item := range strings.Fields(arg) {
+				s.Enum = append(s.Enum, enumValue(s.Type, item))
+			}
+		case "min", "max":
+			if t == durationType {
+				continue
+			}
+			n, err := strconv.ParseFloat(arg, 64)
+			if err != nil {
+				continue
+			}
+			setLimit(s, t.Kind(), name == "min", n)
+		}
+	}
+}
+
+func setLimit(s *Schema, kind reflect.Kind, min bool, n float64) {
+	l := int(n)
+	switch kind {
+	case reflect.String:
+		if min {
+			s.MinLength = &l
+		} else {
+			s.MaxLength = &l
+		}
+	case reflect.Slice, reflect.Array:
+		if min {
+			s.MinItems = &l
+		} else {
+			s.MaxItems = &l
+		}
+	case reflect.Map:
+	default:
+		if min {
+			s.Minimum = &n
+		} else {
+			s.Maximum = &n
+		}
+	}
+}
+
+// schemaValue 把字段值转换为 json 值, time.Duration 输出为 1m30s 的形式
+func schemaValue(v reflect.Value) interface{} {
+	v = reflect.Indirect(v)
+	if v.Type() == durationType {
+		return v.Interface().(fmt.Stringer).String()
+	}
+	var ret interface{}
+	buf, err := jsoniter.Marshal(v.Interface())
+	if err != nil || jsoniter.Unmarshal(buf, &ret) != nil {
+		return fmt.Sprint(v.Interface())
+	}
+	return ret
+}
+
+func enumValue(typ, s string) interface{} {
+	switch typ {
+	case "integer", "number":
+		if n, err := strconv.ParseFloat(s, 64); err == nil {
+			return n
+		}
+	case "boolean":
+		if b, err := strconv.ParseBool(s); err == nil {
+			return b
+		}
+	}
+	return s
+}
+
+func hasRule(tag reflect.StructTag, rule string) bool {
+	for _, item := range strings.Split(tag.Get(TagValidate), ",") {
+		if strings.TrimSpace(item) == rule {
+			return true
+		}
+	}
+	return false
+}
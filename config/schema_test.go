@@ -0,0 +1,52 @@
+package config
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/neura-flow/common/log"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJSONSchema(t *testing.T) {
+	s := JSONSchema(&validateConfig{})
+	assert.Equal(t, SchemaDraft, s.Schema)
+	assert.Equal(t, "object", s.Type)
+	assert.Equal(t, []string{"kind"}, s.Required)
+	assert.Equal(t, []interface{}{"simple", "cluster", "failover"}, s.Properties["kind"].Enum)
+	assert.Equal(t, float64(8882), s.Properties["port"].Default)
+	assert.Equal(t, float64(65535), *s.Properties["port"].Maximum)
+	assert.Equal(t, "^[a-z]+$", s.Properties["name"].Pattern)
+	assert.Equal(t, "3s", s.Properties["timeout"].Default)
+	assert.Equal(t, []interface{}{"a", "b"}, s.Properties["tags"].Default)
+	assert.Equal(t, []string{"maxOpen"}, s.Properties["pool"].Required)
+	assert.Equal(t, "array", s.Properties["servers"].Type)
+	assert.Equal(t, "string", s.Properties["servers"].Items.Properties["addr"].Type)
+
+	s = JSONSchema(log.DefaultConfig)
+	assert.Equal(t, "info", s.Properties["level"].Default)
+	assert.Contains(t, s.Properties["level"].Enum, "debug")
+	assert.Equal(t, true, s.Properties["std"].Properties["enabled"].Default)
+	assert.Equal(t, "日志文件路径", s.Properties["file"].Properties["path"].Description)
+
+	// json 名称中的 '.' 展开为嵌套对象
+	s = JSONSchema(&ArgsDef{})
+	assert.Equal(t, "integer", s.Properties["app"].Properties["port"].Type)
+	assert.Equal(t, []string{"name", "port", "file"}, s.Properties["app"].order)
+
+	buf, err := MarshalSchema(&ArgsDef{})
+	assert.NoError(t, err)
+	assert.Contains(t, string(buf), `"$schema": "`+SchemaDraft+`"`)
+}
+
+func TestMarkdown(t *testing.T) {
+	md := Markdown("log", log.DefaultConfig)
+	lines := strings.Split(strings.TrimSpace(md), "\n")
+	assert.Equal(t, "| key | type | default | description |", lines[0])
+	assert.Contains(t, lines, "| log.level | string | info | 日志级别，默认 info 级别 (debug info warn error fatal panic) |")
+	assert.Contains(t, lines, "| log.file.maxSize | integer |  | 文件大小最大值 |")
+
+	md = Markdown("db", &validateConfig{})
+	assert.Contains(t, md, "| db.servers[].addr | string |  |  |\n")
+	assert.Contains(t, md, "| db.tags | []string | [\"a\",\"b\"] |  |\n")
+}
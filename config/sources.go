@@ -0,0 +1,349 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	kjson "github.com/knadh/koanf/parsers/json"
+	ktoml "github.com/knadh/koanf/parsers/toml/v2"
+	kyaml "github.com/knadh/koanf/parsers/yaml"
+	"github.com/knadh/koanf/providers/rawbytes"
+	"github.com/knadh/koanf/v2"
+	"github.com/neura-flow/common/util"
+	"github.com/samuel/go-zookeeper/zk"
+)
+
+const (
+	SourceDir  = "confd"
+	SourceHTTP = "httpconf"
+	SourceEtcd = "etcd"
+	SourceZk   = "zookeeper"
+
+	DefaultDirPath       = "./configs/conf.d"
+	DefaultEtcdEndpoints = "http://127.0.0.1:2379"
+	DefaultEtcdPrefix    = "config"
+	DefaultZkPath        = "/config"
+	DefaultHTTPTimeout   = 10 * time.Second
+
+	DirEnabled    = "confd.enabled"
+	DirPath       = "confd.dir"
+	HTTPEnabled   = "httpconf.enabled"
+	HTTPUrl       = "httpconf.url"
+	HTTPFormat    = "httpconf.format"
+	HTTPTimeout   = "httpconf.timeout"
+	EtcdEnabled   = "etcd.enabled"
+	EtcdEndpoints = "etcd.endpoints"
+	EtcdPrefix    = "etcd.prefix"
+	EtcdToken     = "etcd.token"
+	ZkEnabled     = "zookeeper.enabled"
+	ZkServers     = "zookeeper.servers"
+	ZkPath        = "zookeeper.path"
+
+	FormatYaml       = "yaml"
+	FormatJson       = "json"
+	FormatToml       = "toml"
+	FormatProperties = "properties"
+)
+
+// parserFor 根据格式或文件扩展名返回解析器
+func parserFor(format string) (koanf.Parser, error) {
+	switch strings.TrimPrefix(strings.ToLower(format), ".") {
+	case FormatYaml, "yml":
+		return kyaml.Parser(), nil
+	case FormatJson:
+		return kjson.Parser(), nil
+	case FormatToml:
+		return ktoml.Parser(), nil
+	case FormatProperties:
+		return PropertiesParser(), nil
+	}
+	return nil, fmt.Errorf("unsupported config format: %s", format)
+}
+
+// loadFlat 把 a.b.c 形式的扁平 kv 展开为嵌套结构后加载
+func loadFlat(k *koanf.Koanf, data map[string]interface{}) error {
+	buf, err := jsoniter.Marshal(newPropertiesToMap(".").Do(data))
+	if err != nil {
+		return err
+	}
+	return k.Load(rawbytes.Provider(buf), kjson.Parser())
+}
+
+type dirSource struct {
+	k   *koanf.Koanf
+	dir string
+}
+
+// NewDirSource 按文件名顺序加载目录下所有支持格式(yaml/json/toml/properties)的文件, 后加载的覆盖先加载的
+func NewDirSource(k *koanf.Koanf, dir string) Source {
+	return &dirSource{
+		k:   k,
+		dir: dir,
+	}
+}
+
+func (s *dirSource) Load() error {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		if _, err := parserFor(filepath.Ext(e.Name())); err == nil {
+			names = append(names, e.Name())
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		parser, _ := parserFor(filepath.Ext(name))
+		buf, err := os.ReadFile(filepath.Join(s.dir, name))
+		if err != nil {
+			return err
+		}
+		if err = s.k.Load(rawbytes.Provider(buf), parser); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+type httpSource struct {
+	k       *koanf.Koanf
+	url     string
+	format  string
+	timeout time.Duration
+}
+
+// NewHTTPSource 通过 GET 请求加载配置, format 为空时根据 Content-Type 或 url 扩展名判断, 默认 yaml
+func NewHTTPSource(k *koanf.Koanf, url, format string, timeout time.Duration) Source {
+	if timeout <= 0 {
+		timeout = DefaultHTTPTimeout
+	}
+	return &httpSource{
+		k:       k,
+		url:     url,
+		format:  format,
+		timeout: timeout,
+	}
+}
+
+func (s *httpSource) Load() error {
+	if util.IsBlank(&s.url) {
+		return errors.New("http config url is required")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	buf, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to load config from %s, status: %d body: %s", s.url, resp.StatusCode, string(buf))
+	}
+	parser, err := parserFor(s.detectFormat(resp.Header.Get("Content-Type")))
+	if err != nil {
+		return err
+	}
+	return s.k.Load(rawbytes.Provider(buf), parser)
+}
+
+func (s *httpSource) detectFormat(contentType string) string {
+	if s.format != "" {
+		return s.format
+	}
+	if strings.Contains(contentType, "json") {
+		return FormatJson
+	}
+	if strings.HasSuffix(strings.SplitN(s.url, "?", 2)[0], ".json") {
+		return FormatJson
+	}
+	return FormatYaml
+}
+
+type etcdSource struct {
+	k         *koanf.Koanf
+	endpoints []string
+	token     string
+	prefix    string
+}
+
+// NewEtcdSource 通过 etcd v3 的 http 网关读取 prefix 下的所有 key, key 中的 '/' 转换为 '.'
+func NewEtcdSource(k *koanf.Koanf, endpoints, token, prefix string) Source {
+	return &etcdSource{
+		k:         k,
+		endpoints: util.ExpandAddress([]string{endpoints}),
+		token:     token,
+		prefix:    strings.TrimSuffix(prefix, "/") + "/",
+	}
+}
+
+type etcdRangeResponse struct {
+	Kvs []struct {
+		Key   string `json:"key"`
+		Value string `json:"value"`
+	} `json:"kvs"`
+}
+
+func (s *etcdSource) Load() error {
+	if len(s.endpoints) == 0 {
+		return errors.New("etcd endpoints is required")
+	}
+	var err error
+	for _, endpoint := range s.endpoints {
+		var resp *etcdRangeResponse
+		if resp, err = s.rangePrefix(endpoint); err == nil {
+			return s.load(resp)
+		}
+	}
+	return err
+}
+
+func (s *etcdSource) rangePrefix(endpoint string) (*etcdRangeResponse, error) {
+	body, _ := jsoniter.Marshal(map[string]string{
+		"key":       base64.StdEncoding.EncodeToString([]byte(s.prefix)),
+		"range_end": base64.StdEncoding.EncodeToString(prefixEnd([]byte(s.prefix))),
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultHTTPTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(endpoint, "/")+"/v3/kv/range", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.token != "" {
+		req.Header.Set("Authorization", s.token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	buf, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to range etcd %s, status: %d body: %s", endpoint, resp.StatusCode, string(buf))
+	}
+	ret := &etcdRangeResponse{}
+	if err = jsoniter.Unmarshal(buf, ret); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+func (s *etcdSource) load(resp *etcdRangeResponse) error {
+	data := make(map[string]interface{}, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		key, err := base64.StdEncoding.DecodeString(kv.Key)
+		if err != nil {
+			return err
+		}
+		value, err := base64.StdEncoding.DecodeString(kv.Value)
+		if err != nil {
+			return err
+		}
+		name := strings.Trim(strings.TrimPrefix(string(key), s.prefix), "/")
+		if name != "" {
+			data[strings.ReplaceAll(name, "/", ".")] = string(value)
+		}
+	}
+	return loadFlat(s.k, data)
+}
+
+// prefixEnd 返回前缀查询的 range_end, 即前缀最后一个可递增的字节加 1
+func prefixEnd(prefix []byte) []byte {
+	end := make([]byte, len(prefix))
+	copy(end, prefix)
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] < 0xff {
+			end[i]++
+			return end[:i+1]
+		}
+	}
+	return []byte{0}
+}
+
+type zkSource struct {
+	k       *koanf.Koanf
+	servers string
+	path    string
+}
+
+// NewZkSource 递归读取 zookeeper path 下的节点, 叶子节点的路径作为 key, 数据作为 value
+func NewZkSource(k *koanf.Koanf, servers, path string) Source {
+	return &zkSource{
+		k:       k,
+		servers: servers,
+		path:    path,
+	}
+}
+
+func (s *zkSource) Load() error {
+	servers := util.ExpandAddress([]string{s.servers})
+	if len(servers) == 0 {
+		return errors.New("zk servers is required")
+	}
+	conn, _, err := zk.Connect(servers, 5*time.Second)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	data := make(map[string]interface{})
+	if err = s.read(conn, s.path, "", data); err != nil {
+		if errors.Is(err, zk.ErrNoNode) {
+			return nil
+		}
+		return err
+	}
+	return loadFlat(s.k, data)
+}
+
+func (s *zkSource) read(conn *zk.Conn, path, key string, data map[string]interface{}) error {
+	children, _, err := conn.Children(path)
+	if err != nil {
+		return err
+	}
+	if len(children) == 0 {
+		if key != "" {
+			value, _, err := conn.Get(path)
+			if err != nil {
+				return err
+			}
+			data[key] = string(value)
+		}
+		return nil
+	}
+	for _, child := range children {
+		if err = s.read(conn, strings.TrimSuffix(path, "/")+"/"+child, join(key, child), data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,263 @@
+package config
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+const (
+	TagDefault  = "default"
+	TagValidate = "validate"
+	TagRegex    = "regex"
+)
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// FieldError 单个配置项的校验错误, Key 为以 json tag 拼接的完整路径
+type FieldError struct {
+	Key string
+	Msg string
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Key, e.Msg)
+}
+
+// ValidationError 汇总所有配置项的校验错误
+type ValidationError struct {
+	Errors []*FieldError
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, 0, len(e.Errors))
+	for _, fe := range e.Errors {
+		msgs = append(msgs, fe.Error())
+	}
+	return fmt.Sprintf("invalid config: %s", strings.Join(msgs, "; "))
+}
+
+// Validate 为零值字段填充 default tag 中的默认值, 然后按以下 tag 校验, 返回包含所有错误配置项的 ValidationError
+//
+//	default:"8882"                   # 默认值, 切片可用逗号分隔或 json 格式
+//	validate:"required,min=1,max=10" # 必填, 数值大小或字符串/切片/map 长度范围
+//	validate:"oneof=simple cluster"  # 可选值, 空格分隔
+//	regex:"^[a-z]+$"                 # 字符串需匹配的正则
+//
+// prefix 为 v 在整个配置中的路径, 用于错误信息
+func Validate(prefix string, v interface{}) error {
+	w := &validator{}
+	w.walk(prefix, reflect.ValueOf(v))
+	if len(w.errs) > 0 {
+		return &ValidationError{Errors: w.errs}
+	}
+	return nil
+}
+
+type validator struct {
+	errs []*FieldError
+}
+
+func (w *validator) fail(key string, format string, args ...interface{}) {
+	w.errs = append(w.errs, &FieldError{Key: key, Msg: fmt.Sprintf(format, args...)})
+}
+
+func (w *validator) walk(key string, v reflect.Value) {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Struct:
+		w.walkStruct(key, v)
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			w.walk(join(key, strconv.Itoa(i)), v.Index(i))
+		}
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			w.walk(join(key, fmt.Sprint(iter.Key().Interface())), iter.Value())
+		}
+	}
+}
+
+func (w *validator) walkStruct(key string, v reflect.Value) {
+	rt := v.Type()
+	for i := 0; i < v.NumField(); i++ {
+		ft := rt.Field(i)
+		if ft.PkgPath != "" {
+			continue
+		}
+		name := strings.Split(ft.Tag.Get("json"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		fkey := key
+		if !ft.Anonymous || name != "" {
+			if name == "" {
+				name = ft.Name
+			}
+			fkey = join(key, name)
+		}
+		f := v.Field(i)
+		if def, ok := ft.Tag.Lookup(TagDefault); ok && f.IsZero() && f.CanSet() {
+			if err := setDefault(f, def); err != nil {
+				w.fail(fkey, "invalid default %q: %v", def, err)
+			}
+		}
+		w.check(fkey, f, ft.Tag)
+		w.walk(fkey, f)
+	}
+}
+
+func (w *validator) check(key string, f reflect.Value, tag reflect.StructTag) {
+	for _, rule := range strings.Split(tag.Get(TagValidate), ",") {
+		name, arg, _ := strings.Cut(strings.TrimSpace(rule), "=")
+		switch name {
+		case "":
+		case "required":
+			if f.IsZero() {
+				w.fail(key, "is required")
+			}
+		case "min", "max":
+			if f.IsZero() {
+				// 未配置的可选项不做范围校验, 必填由 required 控制
+				continue
+			}
+			n, limit, err := measure(f, arg)
+			if err != nil {
+				w.fail(key, "invalid %s %q: %v", name, arg, err)
+			} else if name == "min" && n < limit {
+				w.fail(key, "must be >= %s, got %v", arg, display(f))
+			} else if name == "max" && n > limit {
+				w.fail(key, "must be <= %s, got %v", arg, display(f))
+			}
+		case "oneof":
+			if f.IsZero() {
+				continue
+			}
+			s := fmt.Sprint(reflect.Indirect(f).Interface())
+			if !contains(strings.Fields(arg), s) {
+				w.fail(key, "must be one of [%s], got %q", arg, s)
+			}
+		default:
+			w.fail(key, "unknown validate rule %q", name)
+		}
+	}
+	if pattern, ok := tag.Lookup(TagRegex); ok {
+		f = reflect.Indirect(f)
+		if f.Kind() != reflect.String || f.String() == "" {
+			return
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			w.fail(key, "invalid regex %q: %v", pattern, err)
+		} else if !re.MatchString(f.String()) {
+			w.fail(key, "%q does not match %q", f.String(), pattern)
+		}
+	}
+}
+
+// measure 返回字段用于比较大小的值: 数值本身, 或字符串/切片/map 的长度
+func measure(f reflect.Value, arg string) (n float64, limit float64, err error) {
+	f = reflect.Indirect(f)
+	if f.Type() == durationType {
+		d, err := time.ParseDuration(arg)
+		return float64(f.Int()), float64(d), err
+	}
+	limit, err = strconv.ParseFloat(arg, 64)
+	if err != nil {
+		return 0, 0, err
+	}
+	switch f.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n = float64(f.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n = float64(f.Uint())
+	case reflect.Float32, reflect.Float64:
+		n = f.Float()
+	case reflect.String, reflect.Slice, reflect.Array, reflect.Map:
+		n = float64(f.Len())
+	default:
+		err = fmt.Errorf("unsupported kind %s", f.Kind())
+	}
+	return
+}
+
+func display(f reflect.Value) interface{} {
+	f = reflect.Indirect(f)
+	switch f.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return fmt.Sprintf("length %d", f.Len())
+	}
+	return f.Interface()
+}
+
+// setDefault 把 default tag 的字符串值写入字段
+func setDefault(f reflect.Value, def string) error {
+	if f.Kind() == reflect.Ptr {
+		v := reflect.New(f.Type().Elem())
+		if err := setDefault(v.Elem(), def); err != nil {
+			return err
+		}
+		f.Set(v)
+		return nil
+	}
+	if f.Type() == durationType {
+		d, err := time.ParseDuration(def)
+		if err != nil {
+			return err
+		}
+		f.SetInt(int64(d))
+		return nil
+	}
+	if u, ok := f.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return u.UnmarshalText([]byte(def))
+	}
+	switch f.Kind() {
+	case reflect.String:
+		f.SetString(def)
+		return nil
+	case reflect.Slice:
+		if f.Type().Elem().Kind() == reflect.String && !strings.HasPrefix(strings.TrimSpace(def), "[") {
+			items := strings.Split(def, ",")
+			s := reflect.MakeSlice(f.Type(), len(items), len(items))
+			for i, item := range items {
+				s.Index(i).SetString(strings.TrimSpace(item))
+			}
+			f.Set(s)
+			return nil
+		}
+	}
+	if _, ok := f.Addr().Interface().(json.Unmarshaler); ok {
+		// 如 types.Int 之类的类型自行处理带引号的值
+		return jsoniter.Unmarshal([]byte(strconv.Quote(def)), f.Addr().Interface())
+	}
+	return jsoniter.UnmarshalFromString(def, f.Addr().Interface())
+}
+
+func join(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,98 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/neura-flow/common/log"
+	"github.com/neura-flow/common/types"
+	"github.com/stretchr/testify/assert"
+)
+
+type validateConfig struct {
+	Kind    string          `json:"kind" validate:"required,oneof=simple cluster failover"`
+	Port    int             `json:"port" default:"8882" validate:"min=1,max=65535"`
+	Name    string          `json:"name" default:"app" regex:"^[a-z]+$"`
+	Timeout time.Duration   `json:"timeout" default:"3s" validate:"max=1m"`
+	Period  types.Duration  `json:"period" default:"5m"`
+	Tags    []string        `json:"tags" default:"a, b"`
+	Pool    *validatePool   `json:"pool"`
+	Servers []validateEntry `json:"servers"`
+}
+
+type validatePool struct {
+	MaxOpen int `json:"maxOpen" validate:"required,min=1"`
+	MaxIdle int `json:"maxIdle" default:"2"`
+}
+
+type validateEntry struct {
+	Addr string `json:"addr" validate:"required"`
+}
+
+func TestConfig_DumpDefaults(t *testing.T) {
+	c, err := NewFromYaml([]byte("db:\n  kind: simple\n  pool:\n    maxOpen: 10\n"))
+	assert.NoError(t, err)
+	var cfg validateConfig
+	assert.NoError(t, c.Dump("db", &cfg))
+	assert.Equal(t, 8882, cfg.Port)
+	assert.Equal(t, "app", cfg.Name)
+	assert.Equal(t, 3*time.Second, cfg.Timeout)
+	assert.Equal(t, types.Duration("5m"), cfg.Period)
+	assert.Equal(t, []string{"a", "b"}, cfg.Tags)
+	assert.Equal(t, 10, cfg.Pool.MaxOpen)
+	assert.Equal(t, 2, cfg.Pool.MaxIdle)
+}
+
+func TestConfig_LogFieldsPlaceholders(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	assert.NoError(t, os.WriteFile(file, []byte("log:\n  fields: host=$${hostname},env=${env:LOG_FIELDS_TEST_ENV}\n"), 0644))
+	t.Setenv("APP_FILE", file)
+	t.Setenv("LOG_FIELDS_TEST_ENV", "prod")
+
+	// $${hostname} 转义后留给 logger 解析
+	c := New()
+	assert.NoError(t, c.ReadConfig())
+	var cfg log.Config
+	assert.NoError(t, c.Dump("log", &cfg))
+	assert.Equal(t, "host=${hostname},env=prod", cfg.Fields)
+
+	assert.NoError(t, os.WriteFile(file, []byte("log:\n  fields: host=${hostname}\n"), 0644))
+	assert.ErrorContains(t, New().ReadConfig(), "unresolved ${hostname}")
+}
+
+func TestConfig_DumpValidate(t *testing.T) {
+	c, err := NewFromYaml([]byte(`db:
+  kind: other
+  port: 70000
+  name: App1
+  pool:
+    maxOpen: 0
+  servers:
+    - addr: a
+    - addr: ""
+`))
+	assert.NoError(t, err)
+	var cfg validateConfig
+	err = c.Dump("db", &cfg)
+	var ve *ValidationError
+	if !assert.True(t, errors.As(err, &ve)) {
+		t.Fatal(err)
+	}
+	keys := make([]string, 0, len(ve.Errors))
+	for _, fe := range ve.Errors {
+		keys = append(keys, fe.Key)
+	}
+	assert.Equal(t, []string{"db.kind", "db.port", "db.name", "db.pool.maxOpen", "db.servers.1.addr"}, keys)
+}
+
+func TestLoadFileValidate(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	assert.NoError(t, os.WriteFile(file, []byte("port: 80\n"), 0644))
+	var cfg validateConfig
+	err := LoadFile(file, &cfg)
+	assert.EqualError(t, err, "invalid config: kind: is required")
+	assert.Equal(t, 80, cfg.Port)
+}
@@ -0,0 +1,237 @@
+package config
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/hashicorp/consul/api"
+	"github.com/knadh/koanf/v2"
+)
+
+const (
+	// reloadDelay 合并短时间内的多次变更事件, 避免编辑器保存文件时重复加载
+	reloadDelay     = 100 * time.Millisecond
+	consulWaitTime  = 5 * time.Minute
+	consulRetryWait = 3 * time.Second
+)
+
+// Watcher 支持变更通知的 Source, 配置变化时调用 notify
+type Watcher interface {
+	Watch(ctx context.Context, notify func()) error
+}
+
+// ChangeFunc 配置项变更回调, old 和 new 为变更前后的值, 不存在时为 nil
+type ChangeFunc func(old, new interface{})
+
+type subscriber struct {
+	key string
+	fn  ChangeFunc
+}
+
+// Subscribe 订阅 key 对应配置项(或子树)的变更, key 为空表示订阅整个配置, 返回取消订阅的函数
+func (c *Config) Subscribe(key string, fn ChangeFunc) (cancel func()) {
+	sub := &subscriber{key: key, fn: fn}
+	c.mu.Lock()
+	c.subscribers = append(c.subscribers, sub)
+	c.mu.Unlock()
+	return func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		for i, s := range c.subscribers {
+			if s == sub {
+				c.subscribers = append(c.subscribers[:i:i], c.subscribers[i+1:]...)
+				break
+			}
+		}
+	}
+}
+
+// Watch 监听配置文件和 consul 的变化并自动重新加载, 需要在 ReadConfig 之后调用, ctx 结束时停止监听.
+// 每次重新加载成功后按新的配置重新监听, 以便跟随配置文件路径、profile 以及 include 的变化
+func (c *Config) Watch(ctx context.Context) error {
+	notifyCh := make(chan struct{}, 1)
+	notify := func() {
+		select {
+		case notifyCh <- struct{}{}:
+		default:
+		}
+	}
+	stop, err := c.watchSources(ctx, notify)
+	if err != nil {
+		return err
+	}
+	go c.reloadLoop(ctx, notifyCh, notify, stop)
+	return nil
+}
+
+// watchSources 根据当前配置创建 Source 并监听其变化, 返回停止监听的函数
+func (c *Config) watchSources(ctx context.Context, notify func()) (context.CancelFunc, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	k := c.Koanf()
+	for _, def := range c.sourceDefs() {
+		if w, ok := def.new(k, koanf.New(".")).(Watcher); ok {
+			if err := w.Watch(ctx, notify); err != nil {
+				cancel()
+				return nil, err
+			}
+		}
+	}
+	return cancel, nil
+}
+
+func (c *Config) reloadLoop(ctx context.Context, notifyCh <-chan struct{}, notify func(), stop context.CancelFunc) {
+	defer func() { stop() }()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-notifyCh:
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(reloadDelay):
+		}
+		select {
+		case <-notifyCh:
+		default:
+		}
+		if err := c.Reload(); err != nil {
+			c.handleError(err)
+			continue
+		}
+		// 先开始新的监听再停止旧的, 避免遗漏其间的变化; 失败时保留旧的监听
+		next, err := c.watchSources(ctx, notify)
+		if err != nil {
+			c.handleError(err)
+			continue
+		}
+		stop()
+		stop = next
+	}
+}
+
+// swap 原子替换配置快照, 然后通知值发生变化的订阅者
+func (c *Config) swap(k *koanf.Koanf, origins map[string]string, encrypted, sensitive map[string]bool) {
+	c.mu.Lock()
+	old := c.k
+	c.k = k
+	c.origins = origins
+	c.encrypted = encrypted
+	c.sensitive = sensitive
+	subs := make([]*subscriber, len(c.subscribers))
+	copy(subs, c.subscribers)
+	c.mu.Unlock()
+
+	for _, s := range subs {
+		ov, nv := value(old, s.key), value(k, s.key)
+		if !reflect.DeepEqual(ov, nv) {
+			s.fn(ov, nv)
+		}
+	}
+}
+
+func value(k *koanf.Koanf, key string) interface{} {
+	if key == "" {
+		return k.Raw()
+	}
+	return k.Get(key)
+}
+
+func (c *Config) handleError(err error) {
+	if c.errorHandler != nil {
+		c.errorHandler(err)
+	}
+}
+
+// Watch 监听配置文件、include 的文件以及 profile 文件的变化
+func (f *fileSource) Watch(ctx context.Context, notify func()) error {
+	paths, err := f.load(koanf.New("."))
+	if err != nil || len(paths) == 0 {
+		return err
+	}
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	files := make(map[string]bool, len(paths))
+	dirs := make(map[string]bool)
+	for _, path := range paths {
+		files[path] = true
+		// 监听所在目录, 以便捕获编辑器先删除再创建(或 rename)文件的保存方式
+		if dir := filepath.Dir(path); !dirs[dir] {
+			dirs[dir] = true
+			if err = w.Add(dir); err != nil {
+				w.Close()
+				return err
+			}
+		}
+	}
+	go func() {
+		defer w.Close()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case e, ok := <-w.Events:
+				if !ok {
+					return
+				}
+				if files[filepath.Clean(e.Name)] && e.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename) != 0 {
+					notify()
+				}
+			case _, ok := <-w.Errors:
+				if !ok {
+					return
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+// Watch 对 application 和应用两个前缀分别发起 consul 阻塞查询, index 变化时通知
+func (s *consulSource) Watch(ctx context.Context, notify func()) error {
+	if !s.enabled {
+		return nil
+	}
+	client, err := s.client()
+	if err != nil {
+		return err
+	}
+	for _, prefix := range s.prefixes() {
+		go s.watchPrefix(ctx, client, prefix, notify)
+	}
+	return nil
+}
+
+func (s *consulSource) watchPrefix(ctx context.Context, client *api.Client, prefix string, notify func()) {
+	var index uint64
+	for {
+		opts := (&api.QueryOptions{WaitIndex: index, WaitTime: consulWaitTime}).WithContext(ctx)
+		_, meta, err := client.KV().List(prefix, opts)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(consulRetryWait):
+			}
+			continue
+		}
+		if index > 0 && meta.LastIndex != index {
+			notify()
+		}
+		// index 回退时(如 consul 快照恢复)需要重置, 否则会一直阻塞
+		if meta.LastIndex < index {
+			index = 0
+		} else {
+			index = meta.LastIndex
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfig_Watch(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	assert.NoError(t, os.WriteFile(file, []byte("database:\n  host: a\n  port: 3306\n"), 0644))
+	t.Setenv("APP_FILE", file)
+
+	c := New()
+	assert.NoError(t, c.ReadConfig())
+	assert.Equal(t, "a", c.Koanf().String("database.host"))
+
+	changed := make(chan [2]interface{}, 1)
+	cancel := c.Subscribe("database.host", func(old, new interface{}) {
+		changed <- [2]interface{}{old, new}
+	})
+	defer cancel()
+	var portChanged int32
+	c.Subscribe("database.port", func(old, new interface{}) {
+		atomic.StoreInt32(&portChanged, 1)
+	})
+
+	ctx, stop := context.WithCancel(context.Background())
+	defer stop()
+	assert.NoError(t, c.Watch(ctx))
+	assert.NoError(t, os.WriteFile(file, []byte("database:\n  host: b\n  port: 3306\n"), 0644))
+
+	select {
+	case v := <-changed:
+		assert.Equal(t, "a", v[0])
+		assert.Equal(t, "b", v[1])
+	case <-time.After(5 * time.Second):
+		t.Fatal("change not notified")
+	}
+	assert.Equal(t, "b", c.Koanf().String("database.host"))
+	assert.Equal(t, int32(0), atomic.LoadInt32(&portChanged))
+}
+
+func TestConfig_WatchNewInclude(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "config.yaml")
+	assert.NoError(t, os.WriteFile(file, []byte("a: 1\n"), 0644))
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, "conf.d"), 0755))
+	extra := filepath.Join(dir, "conf.d", "extra.yaml")
+	assert.NoError(t, os.WriteFile(extra, []byte("b: 1\n"), 0644))
+	t.Setenv("APP_FILE", file)
+
+	c := New()
+	assert.NoError(t, c.ReadConfig())
+	ctx, stop := context.WithCancel(context.Background())
+	defer stop()
+	assert.NoError(t, c.Watch(ctx))
+
+	// 重新加载后开始监听新 include 的文件
+	assert.NoError(t, os.WriteFile(file, []byte("include: conf.d/extra.yaml\na: 2\n"), 0644))
+	assert.Eventually(t, func() bool { return c.Koanf().Int("a") == 2 }, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, 1, c.Koanf().Int("b"))
+	// 等待新的监听生效
+	time.Sleep(50 * time.Millisecond)
+	assert.NoError(t, os.WriteFile(extra, []byte("b: 2\n"), 0644))
+	assert.Eventually(t, func() bool { return c.Koanf().Int("b") == 2 }, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestConfig_ReloadKeepsSnapshotOnError(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	assert.NoError(t, os.WriteFile(file, []byte("a: 1\n"), 0644))
+	t.Setenv("APP_FILE", file)
+
+	c := New()
+	assert.NoError(t, c.ReadConfig())
+	assert.NoError(t, os.WriteFile(file, []byte("a: [1\n"), 0644))
+	assert.Error(t, c.Reload())
+	assert.Equal(t, 1, c.Koanf().Int("a"))
+}
@@ -3,42 +3,80 @@ package election
 import (
 	"context"
 	"errors"
-	"fmt"
-	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/neura-flow/common/log"
-	"github.com/neura-flow/common/util"
-	"github.com/samuel/go-zookeeper/zk"
 )
 
 type Election struct {
-	ctx        context.Context
-	config     *Config
-	logger     log.Logger
-	lock       *ResourceLock
-	resourceId string //resourceId 保存到 zk node 中,用于判断当前的连接是否选主成功的连接
-	isLeader   bool
+	ctx      context.Context
+	config   *Config
+	logger   log.Logger
+	elector  Elector
+	isLeader int32
+	token    int64
+
+	mu      sync.Mutex
+	leader  *LeaderInfo     // 最近一次观察到的 leader
+	term    *term           // 当前任期, 不是 leader 时为空
+	yielded map[string]bool // 已经为其放弃过 leader 的候选成员登记
+
+	// 以下字段只在 Run 的 goroutine 中访问
+	backoffUntil  time.Time // Resign 后在此之前不参与选举
+	deferredSince time.Time // 因存在优先级更高的候选成员而开始推迟获取租约的时间
+}
+
+// term 一次 leader 任期
+type term struct {
+	cancel   context.CancelFunc
+	resigned int32
+	started  chan struct{} // OnStartedLeading 返回时关闭
+	done     chan struct{} // 任期结束(包括 Resign 时释放租约)后关闭
+	err      error         // Resign 时释放租约的错误
+}
+
+func (t *term) resign() {
+	atomic.StoreInt32(&t.resigned, 1)
+	t.cancel()
+}
+
+func (t *term) isResigned() bool {
+	return atomic.LoadInt32(&t.resigned) == 1
 }
 
+type tokenKey struct{}
+
+// FencingToken 返回 OnStartedLeading 的 ctx 中当前任期的 fencing token
+func FencingToken(ctx context.Context) (int64, bool) {
+	token, ok := ctx.Value(tokenKey{}).(int64)
+	return token, ok
+}
+
+// NewElection 创建选举, cfg.Elector 为空时使用 zookeeper 后端, 见 NewZkElector
 func NewElection(ctx context.Context, logger log.Logger, cfg *Config) (*Election, error) {
 	if err := validate(cfg); err != nil {
 		return nil, err
 	}
-	lock, err := NewResourceLock(logger, cfg.ZkServers)
-	if err != nil {
-		return nil, err
+	elector := cfg.Elector
+	if elector == nil {
+		var err error
+		if elector, err = NewZkElector(logger, cfg); err != nil {
+			return nil, err
+		}
 	}
 	return &Election{
-		ctx:        ctx,
-		logger:     logger,
-		config:     cfg,
-		lock:       lock,
-		resourceId: util.GUID(),
+		ctx:     ctx,
+		logger:  logger,
+		config:  cfg,
+		elector: elector,
 	}, nil
 }
 
+// Run 参与选举直到 ctx 取消, 同时在后台观察 leader 的变化
 func (le *Election) Run() {
+	go le.observe(le.ctx)
 	for {
 		select {
 		case <-le.ctx.Done():
@@ -51,223 +89,341 @@ func (le *Election) Run() {
 }
 
 func (le *Election) run(ctx context.Context) {
-	defer func() {
-		le.isLeader = false
-		le.config.Callbacks.OnStoppedLeading()
-	}()
-
-	acquired, err := le.acquire()
+	if backoff := time.Until(le.backoffUntil); backoff > 0 {
+		le.sleep(ctx, backoff)
+		return
+	}
+	if !le.preferred(ctx) {
+		le.wait(ctx)
+		return
+	}
+	acquired, err := le.elector.Acquire(ctx, LeaderInfo{
+		Identity:  le.config.Identity,
+		Address:   le.config.Address,
+		StartedAt: time.Now(),
+		Metadata:  le.config.Metadata,
+	})
 	if err != nil || !acquired {
-		time.Sleep(time.Duration(2) * time.Second)
+		if err != nil {
+			le.logger.Debugf("%s failed to acquire leader lease, err: %v", le.config.Identity, err)
+		} else {
+			le.deferredSince = time.Time{}
+		}
+		le.wait(ctx)
 		return
 	}
-
-	le.isLeader = true
-	ctx, cancel := context.WithCancel(ctx)
-	defer func() {
-		cancel()
-	}()
-
-	go le.config.Callbacks.OnStartedLeading(ctx)
-
-	err = le.watch(ctx)
-	if err != nil {
+	le.deferredSince = time.Time{}
+	le.logger.Infof("%s acquired the leader lease", le.config.Identity)
+	if err = le.lead(ctx); err != nil {
 		le.logger.Debugf("failed to renew %s leader lease,err: %v", le.config.Identity, err)
-		time.Sleep(time.Duration(2) * time.Second)
-		return
+		le.wait(ctx)
 	}
 }
 
-func validate(cfg *Config) error {
-	if cfg == nil {
-		return errors.New("cfg is required")
-	}
-	if util.IsBlank(&cfg.ZkServers) {
-		return errors.New("zk servers is required")
-	}
-	if util.IsBlank(&cfg.ElectionRoot) {
-		return errors.New("root path is required")
+// preferred 返回是否应该尝试获取租约. 存在优先级更高的候选成员时推迟 2 个 RetryPeriod, 让其先获取租约,
+// 超过后仍未产生 leader 时不再推迟, 避免优先级更高的成员异常时没有 leader
+func (le *Election) preferred(ctx context.Context) bool {
+	higher, err := le.higherCandidates(ctx)
+	if err != nil {
+		le.logger.Debugf("%s failed to get candidates, err: %v", le.config.Identity, err)
 	}
-	if !strings.HasPrefix(cfg.ElectionRoot, "/") {
-		return errors.New("root path should begin with '/'")
+	if len(higher) == 0 {
+		le.deferredSince = time.Time{}
+		return true
 	}
-	if util.IsBlank(&cfg.ElectionID) {
-		return errors.New("leaderElectionID is required")
+	if le.deferredSince.IsZero() {
+		le.deferredSince = time.Now()
 	}
-	return nil
+	return time.Since(le.deferredSince) >= 2*le.retryPeriod()
 }
 
-func (le *Election) IsLeader() bool {
-	return le.isLeader
+func (le *Election) higherCandidates(ctx context.Context) ([]string, error) {
+	c, ok := le.elector.(candidates)
+	if !ok {
+		return nil, nil
+	}
+	return c.higherCandidates(ctx, le.config.Identity, le.config.Priority)
 }
 
-func (le *Election) acquire() (bool, error) {
-	if err := le.ensureRoot(); err != nil {
-		return false, err
-	}
-	fullElectionID := le.getFullElectionID()
-	if err := le.elected(fullElectionID); err == nil {
-		return true, nil
-	} else if errors.Is(err, zk.ErrNoNode) {
-		created, err := le.lock.Create(fullElectionID, []byte(le.resourceId), FlagEphemeral)
-		if err != nil {
-			return false, err
-		}
-		if !strings.EqualFold(created, fullElectionID) {
-			return false, fmt.Errorf("created node mismatch, want: %s created: %s", fullElectionID, created)
-		}
+// withdrawCandidate Resign 后撤销候选成员登记, 避免其他成员在 ResignBackoff 期间推迟获取租约或为自己放弃 leader,
+// 重新参与选举时再次登记
+func (le *Election) withdrawCandidate() {
+	c, ok := le.elector.(candidates)
+	if !ok {
+		return
 	}
-	if err := le.elected(fullElectionID); err != nil {
-		return false, err
+	if err := c.withdrawCandidate(le.ctx); err != nil {
+		le.logger.Debugf("%s failed to withdraw candidate, err: %v", le.config.Identity, err)
 	}
-	le.logger.Infof("%s acquired the leader lease", le.config.Identity)
-	return true, nil
 }
 
-func (le *Election) elected(fullElectionID string) error {
-	if data, err := le.lock.Get(fullElectionID); err != nil {
-		return err
-	} else if !strings.EqualFold(string(data), le.resourceId) {
-		return fmt.Errorf("failed to acquire %s", fullElectionID)
+// lead 持有租约期间执行 OnStartedLeading, 租约丢失、Resign 或 ctx 取消后执行 OnStoppedLeading.
+// 连接中断等原因导致 Hold 返回时 OnStartedLeading 的 ctx 会被取消
+func (le *Election) lead(ctx context.Context) error {
+	token := le.elector.Token()
+	atomic.StoreInt64(&le.token, token)
+	atomic.StoreInt32(&le.isLeader, 1)
+	ctx, cancel := context.WithCancel(context.WithValue(ctx, tokenKey{}, token))
+	t := &term{cancel: cancel, started: make(chan struct{}), done: make(chan struct{})}
+	le.mu.Lock()
+	le.term = t
+	le.mu.Unlock()
+	defer func() {
+		cancel()
+		resigned := t.isResigned()
+		if resigned {
+			le.waitStarted(t)
+		}
+		atomic.StoreInt32(&le.isLeader, 0)
+		if le.config.Callbacks.OnStoppedLeading != nil {
+			le.config.Callbacks.OnStoppedLeading()
+		}
+		if resigned {
+			le.withdrawCandidate()
+			t.err = le.elector.Release(le.ctx)
+			le.backoffUntil = time.Now().Add(le.resignBackoff())
+			le.logger.Infof("%s resigned the leader lease", le.config.Identity)
+		}
+		le.mu.Lock()
+		le.term = nil
+		le.mu.Unlock()
+		close(t.done)
+	}()
+
+	go func() {
+		defer close(t.started)
+		if le.config.Callbacks.OnStartedLeading != nil {
+			le.config.Callbacks.OnStartedLeading(ctx)
+		}
+	}()
+	if _, ok := le.elector.(candidates); ok {
+		go le.yield(ctx, t)
 	}
-	return nil
+	return le.elector.Hold(ctx)
 }
 
-func (le *Election) watch(ctx context.Context) error {
-	childCh, err := le.lock.Watch(le.getFullElectionID())
-	if err != nil {
-		return err
-	}
+// yield 持有租约期间按 RetryPeriod 检查候选成员, 存在优先级更高的候选成员时放弃 leader
+func (le *Election) yield(ctx context.Context, t *term) {
+	ticker := time.NewTicker(le.retryPeriod())
+	defer ticker.Stop()
 	for {
 		select {
-		case event := <-childCh:
-			if event.Type == zk.EventNodeDeleted {
-				return errors.New(event.Type.String())
-			} else if event.State != zk.StateConnected && event.State != zk.StateHasSession {
-				return errors.New(event.State.String())
-			} else {
-				continue
-			}
 		case <-ctx.Done():
-			le.logger.Infof("%s receive cancel", le.config.Identity)
-			return nil
+			return
+		case <-ticker.C:
+			higher, err := le.higherCandidates(ctx)
+			if err == nil && le.shouldYield(higher) {
+				le.logger.Infof("%s found a candidate with higher priority, resign", le.config.Identity)
+				t.resign()
+				return
+			}
 		}
 	}
 }
 
-func (le *Election) release() {
-	if le.lock != nil {
-		le.lock.Close()
+// shouldYield 返回是否为 higher 中的候选成员放弃 leader. 对同一次候选成员登记只放弃一次,
+// 避免优先级更高的成员无法获取租约(如 Acquire 一直失败)时反复放弃, 该成员 Resign 或重新登记后才会再次放弃
+func (le *Election) shouldYield(higher []string) bool {
+	le.mu.Lock()
+	defer le.mu.Unlock()
+	yield := false
+	yielded := make(map[string]bool, len(higher))
+	for _, id := range higher {
+		if !le.yielded[id] {
+			yield = true
+		}
+		yielded[id] = true
 	}
+	le.yielded = yielded
+	return yield
 }
 
-func (le *Election) ensureRoot() error {
-	if exists, err := le.lock.Exists(le.config.ElectionRoot); err != nil {
-		return err
-	} else if !exists {
-		created, err := le.lock.Create(le.config.ElectionRoot, nil, FlagPermanent)
-		if err != nil {
-			return err
-		}
-		if !strings.EqualFold(created, le.config.ElectionRoot) {
-			return fmt.Errorf("failed to created root node, identity: %s want: %s created: %s", le.config.Identity, le.config.ElectionRoot, created)
-		}
+// waitStarted 等待 OnStartedLeading 返回, 最多等待 StopTimeout
+func (le *Election) waitStarted(t *term) {
+	timeout := le.config.StopTimeout
+	if timeout <= 0 {
+		timeout = DefaultStopTimeout
+	}
+	select {
+	case <-t.started:
+	case <-time.After(timeout):
+		le.logger.Warnf("%s OnStartedLeading not returned in %s", le.config.Identity, timeout)
 	}
-	return nil
 }
 
-func (le *Election) getFullElectionID() string {
-	return fmt.Sprintf("%s/%s", le.config.ElectionRoot, le.config.ElectionID)
+// Resign 主动放弃 leader: 取消 OnStartedLeading 的 ctx 并等待其返回(最多 StopTimeout), 执行 OnStoppedLeading 后释放租约,
+// 其他成员可以立即获取租约, 自己在 ResignBackoff 后重新参与选举. 不是 leader 时直接返回 nil, ctx 用于控制等待的时间
+func (le *Election) Resign(ctx context.Context) error {
+	le.mu.Lock()
+	t := le.term
+	le.mu.Unlock()
+	if t == nil {
+		return nil
+	}
+	t.resign()
+	select {
+	case <-t.done:
+		return t.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
 
-type Config struct {
-	ZkServers    string
-	ElectionRoot string
-	ElectionID   string
-	Callbacks    Callbacks
-	Identity     string
+// wait 等待 RetryPeriod 后重新参与选举
+func (le *Election) wait(ctx context.Context) {
+	le.sleep(ctx, le.retryPeriod())
 }
 
-type Callbacks struct {
-	OnStartedLeading func(context.Context)
-	OnStoppedLeading func()
+func (le *Election) sleep(ctx context.Context, d time.Duration) {
+	select {
+	case <-ctx.Done():
+	case <-time.After(d):
+	}
 }
 
-type ResourceLock struct {
-	logger log.Logger
-	conn   *zk.Conn
-	clean  func()
+func (le *Election) retryPeriod() time.Duration {
+	if le.config.RetryPeriod > 0 {
+		return le.config.RetryPeriod
+	}
+	return DefaultRetryPeriod
 }
 
-func NewResourceLock(logger log.Logger, zkServers string) (lock *ResourceLock, err error) {
-	servers := strings.Split(zkServers, ",")
-	if len(servers) == 0 {
-		err = errors.New("zk servers is required")
-		return
+func (le *Election) resignBackoff() time.Duration {
+	if le.config.ResignBackoff > 0 {
+		return le.config.ResignBackoff
 	}
-	conn, event, err := zk.Connect(servers, time.Second)
-	if err != nil {
-		return nil, err
-	}
-	// 等待连接成功
-	for {
-		isConnected := false
-		select {
-		case connEvent := <-event:
-			if connEvent.State == zk.StateConnected {
-				isConnected = true
-				logger.Infof("connect to zookeeper server success!")
-			}
-		case <-time.After(time.Second * 3):
-			// 3秒仍未连接成功则返回连接超时
-			return nil, errors.New("connect to zookeeper server timeout")
+	return 2 * le.retryPeriod()
+}
+
+// leaderWatcher 支持监听 leader 变化的后端, 其他后端按 RetryPeriod 轮询 Leader
+type leaderWatcher interface {
+	// watchLeader 返回当前 leader 和 leader 可能发生变化时关闭的 channel
+	watchLeader(ctx context.Context) (LeaderInfo, bool, <-chan struct{}, error)
+}
+
+// candidates 支持候选成员优先级的后端, 见 Config.Priority
+type candidates interface {
+	// higherCandidates 以 priority 登记为候选成员(priority 为 0 时不登记, 已登记时不重复登记),
+	// 返回优先级更高的其他候选成员的登记 id, 每次登记的 id 不同
+	higherCandidates(ctx context.Context, identity string, priority int) ([]string, error)
+	// withdrawCandidate 撤销自己的候选成员登记
+	withdrawCandidate(ctx context.Context) error
+}
+
+// observe 观察 leader 的变化, 更新 Leader 的结果并调用 OnNewLeader
+func (le *Election) observe(ctx context.Context) {
+	watcher, _ := le.elector.(leaderWatcher)
+	for ctx.Err() == nil {
+		var (
+			leader  LeaderInfo
+			ok      bool
+			changed <-chan struct{}
+			err     error
+		)
+		if watcher != nil {
+			leader, ok, changed, err = watcher.watchLeader(ctx)
+		} else {
+			leader, ok, err = le.elector.Leader(ctx)
+		}
+		if err != nil {
+			le.logger.Debugf("%s failed to get leader, err: %v", le.config.Identity, err)
+		} else {
+			le.setLeader(leader, ok)
+		}
+		if changed == nil {
+			le.wait(ctx)
+			continue
 		}
-		if isConnected {
-			break
+		select {
+		case <-ctx.Done():
+		case <-changed:
 		}
 	}
-	lock = &ResourceLock{
-		conn: conn,
-		clean: func() {
-			conn.Close()
-		},
+}
+
+func (le *Election) setLeader(leader LeaderInfo, ok bool) {
+	le.mu.Lock()
+	previous := le.leader
+	if ok {
+		le.leader = &leader
+	} else {
+		le.leader = nil
+	}
+	le.mu.Unlock()
+	if !ok || (previous != nil && previous.Identity == leader.Identity && previous.StartedAt.Equal(leader.StartedAt)) {
+		return
+	}
+	le.logger.Infof("%s observed new leader %s", le.config.Identity, leader.Identity)
+	if le.config.Callbacks.OnNewLeader != nil {
+		le.config.Callbacks.OnNewLeader(leader.Identity)
 	}
-	return
 }
 
-func (r *ResourceLock) Close() {
-	if r.clean != nil {
-		r.clean()
+// Leader 返回最近一次观察到的 leader, 没有 leader 或 Run 未执行时返回 false
+func (le *Election) Leader() (LeaderInfo, bool) {
+	le.mu.Lock()
+	defer le.mu.Unlock()
+	if le.leader == nil {
+		return LeaderInfo{}, false
 	}
+	return *le.leader, true
 }
 
-const (
-	FlagPermanent = 0                // 0: 永久保存
-	FlagEphemeral = zk.FlagEphemeral // 1: 短暂,session断开则该节点也被删除
-)
+func validate(cfg *Config) error {
+	if cfg == nil {
+		return errors.New("cfg is required")
+	}
+	return nil
+}
 
-func (r *ResourceLock) Create(path string, data []byte, flags int32) (string, error) {
-	return r.conn.Create(path, data, flags, zk.WorldACL(zk.PermAll))
+func (le *Election) IsLeader() bool {
+	return atomic.LoadInt32(&le.isLeader) == 1
 }
 
-func (r *ResourceLock) Exists(path string) (exists bool, err error) {
-	exists, _, err = r.conn.Exists(path)
-	return
+// FencingToken 返回最近一次成为 leader 时的 fencing token, 未成为过 leader 时返回 0
+func (le *Election) FencingToken() int64 {
+	return atomic.LoadInt64(&le.token)
 }
 
-func (r *ResourceLock) Get(path string) (data []byte, err error) {
-	data, _, err = r.conn.Get(path)
-	return
+func (le *Election) release() {
+	if err := le.elector.Close(); err != nil {
+		le.logger.Warnf("%s failed to release leader lease, err: %v", le.config.Identity, err)
+	}
 }
 
-func (r *ResourceLock) Watch(path string) (childCh <-chan zk.Event, err error) {
-	_, _, childCh, err = r.conn.ChildrenW(path)
-	return
+type Config struct {
+	ZkServers    string
+	ElectionRoot string
+	ElectionID   string
+	Callbacks    Callbacks
+	Identity     string
+	// Elector 选主后端, 为空时使用 zookeeper, 见 NewZkElector、NewRedisElector、NewSQLElector、MemoryStore
+	Elector Elector
+	// RetryPeriod 未获取到租约或失去租约后重新参与选举的间隔, 默认 2s
+	RetryPeriod time.Duration
+	// SessionTimeout zookeeper session 超时时间, 默认 1s
+	SessionTimeout time.Duration
+	// SuspendGrace zookeeper 连接断开后 leader 保留身份的时间, 超过后取消 OnStartedLeading 的 ctx, 默认为 SessionTimeout
+	SuspendGrace time.Duration
+	// Address 成为 leader 后写入存储的地址, 其他成员可以通过 Leader 获取并转发请求
+	Address string
+	// Metadata 成为 leader 后写入存储的其他信息
+	Metadata map[string]string
+	// StopTimeout Resign 时等待 OnStartedLeading 返回的时间, 超时后仍然释放租约, 默认 10s
+	StopTimeout time.Duration
+	// ResignBackoff Resign 后重新参与选举的间隔, 默认为 2 倍 RetryPeriod
+	ResignBackoff time.Duration
+	// Priority 候选成员优先级, 默认 0. 存在优先级更高的候选成员时, leader 会主动 Resign(对每个候选成员只放弃一次),
+	// 其他成员推迟获取租约, 用于滚动发布时把 leader 交给已升级的成员. 仅 zookeeper 和 MemoryStore 后端支持
+	Priority int
 }
 
-func (r *ResourceLock) IsConnected() bool {
-	if r.conn == nil || r.conn.State() != zk.StateConnected {
-		return false
-	}
-	return true
+type Callbacks struct {
+	// OnStartedLeading 成为 leader 后执行, ctx 在失去 leader 时取消, 可以通过 FencingToken(ctx) 获取当前任期的 token
+	OnStartedLeading func(context.Context)
+	OnStoppedLeading func()
+	// OnNewLeader 观察到新的 leader(包括同一成员重新成为 leader)时调用, 所有成员(包括 leader 自己)都会调用
+	OnNewLeader func(identity string)
+	// OnConnStateChange zookeeper 连接状态变化时调用
+	OnConnStateChange func(ConnState)
 }
@@ -0,0 +1,318 @@
+package election
+
+import (
+	"context"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/neura-flow/common/log"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func newTestElection(t *testing.T, ctx context.Context, identity string, elector Elector, started chan<- string) *Election {
+	le, err := NewElection(ctx, log.DefaultLogger(), &Config{
+		Identity:    identity,
+		Elector:     elector,
+		RetryPeriod: 10 * time.Millisecond,
+		Callbacks: Callbacks{
+			OnStartedLeading: func(context.Context) { started <- identity },
+			OnStoppedLeading: func() {},
+		},
+	})
+	assert.NoError(t, err)
+	return le
+}
+
+func TestElection_Memory(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	store := NewMemoryStore()
+	started := make(chan string, 4)
+	a := newTestElection(t, ctx, "a", store.Elector("job"), started)
+	b := newTestElection(t, ctx, "b", store.Elector("job"), started)
+	go a.Run()
+	assert.Equal(t, "a", <-started)
+	go b.Run()
+
+	select {
+	case id := <-started:
+		t.Fatalf("%s should not lead", id)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	assert.True(t, store.Revoke("job"))
+	select {
+	case <-started:
+	case <-time.After(time.Second):
+		t.Fatal("no new leader after revoke")
+	}
+}
+
+func TestElection_Leader(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	store := NewMemoryStore()
+	observed := make(chan string, 8)
+	newElection := func(ctx context.Context, identity string) *Election {
+		le, err := NewElection(ctx, log.DefaultLogger(), &Config{
+			Identity:    identity,
+			Address:     identity + ":8080",
+			Elector:     store.Elector("job"),
+			RetryPeriod: 10 * time.Millisecond,
+			Callbacks: Callbacks{
+				OnNewLeader: func(leader string) { observed <- identity + "->" + leader },
+			},
+		})
+		assert.NoError(t, err)
+		return le
+	}
+	ctxA, cancelA := context.WithCancel(ctx)
+	a := newElection(ctxA, "a")
+	go a.Run()
+	assert.Equal(t, "a->a", <-observed)
+	b := newElection(ctx, "b")
+	go b.Run()
+	assert.Equal(t, "b->a", <-observed)
+	leader, ok := b.Leader()
+	assert.True(t, ok)
+	assert.Equal(t, "a:8080", leader.Address)
+
+	// a 退出后由 b 接管
+	cancelA()
+	select {
+	case o := <-observed:
+		assert.Equal(t, "b->b", o)
+	case <-time.After(time.Second):
+		t.Fatal("new leader not observed")
+	}
+	leader, _ = b.Leader()
+	assert.Equal(t, "b", leader.Identity)
+}
+
+func TestElection_Resign(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	store := NewMemoryStore()
+	started := make(chan string, 4)
+	var returned int32
+	a, err := NewElection(ctx, log.DefaultLogger(), &Config{
+		Identity:    "a",
+		Elector:     store.Elector("job"),
+		RetryPeriod: 10 * time.Millisecond,
+		Callbacks: Callbacks{
+			OnStartedLeading: func(ctx context.Context) {
+				started <- "a"
+				<-ctx.Done()
+				time.Sleep(20 * time.Millisecond)
+				atomic.StoreInt32(&returned, 1)
+			},
+		},
+	})
+	assert.NoError(t, err)
+	b := newTestElection(t, ctx, "b", store.Elector("job"), started)
+	assert.NoError(t, b.Resign(ctx))
+
+	go a.Run()
+	assert.Equal(t, "a", <-started)
+	go b.Run()
+	assert.NoError(t, a.Resign(ctx))
+	// Resign 等待 OnStartedLeading 返回后才释放租约
+	assert.Equal(t, int32(1), atomic.LoadInt32(&returned))
+	assert.False(t, a.IsLeader())
+	select {
+	case id := <-started:
+		assert.Equal(t, "b", id)
+	case <-time.After(time.Second):
+		t.Fatal("no new leader after resign")
+	}
+}
+
+func TestElection_Priority(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	store := NewMemoryStore()
+	started := make(chan string, 4)
+	a := newTestElection(t, ctx, "a", store.Elector("job"), started)
+	go a.Run()
+	assert.Equal(t, "a", <-started)
+
+	// 优先级更高的 b 加入后 a 主动放弃 leader
+	b := newTestElection(t, ctx, "b", store.Elector("job"), started)
+	b.config.Priority = 1
+	go b.Run()
+	select {
+	case id := <-started:
+		assert.Equal(t, "b", id)
+	case <-time.After(time.Second):
+		t.Fatal("leader not handed over to preferred candidate")
+	}
+	select {
+	case id := <-started:
+		t.Fatalf("%s should not lead", id)
+	case <-time.After(100 * time.Millisecond):
+	}
+	assert.True(t, b.IsLeader())
+	assert.False(t, a.IsLeader())
+}
+
+func TestElection_PriorityCandidateCannotAcquire(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	store := NewMemoryStore()
+	started := make(chan string, 8)
+	a := newTestElection(t, ctx, "a", store.Elector("job"), started)
+	go a.Run()
+	assert.Equal(t, "a", <-started)
+
+	// 登记了更高优先级但从不获取租约的成员, a 只放弃一次
+	stuck := store.Elector("job").(*memoryElector)
+	_, err := stuck.higherCandidates(ctx, "stuck", 1)
+	assert.NoError(t, err)
+	select {
+	case id := <-started:
+		assert.Equal(t, "a", id)
+	case <-time.After(time.Second):
+		t.Fatal("leader not reacquired after yield")
+	}
+	select {
+	case id := <-started:
+		t.Fatalf("%s should not lead again", id)
+	case <-time.After(200 * time.Millisecond):
+	}
+	assert.True(t, a.IsLeader())
+
+	// 重新登记后视为新的候选成员
+	assert.NoError(t, stuck.withdrawCandidate(ctx))
+	_, err = stuck.higherCandidates(ctx, "stuck", 1)
+	assert.NoError(t, err)
+	select {
+	case id := <-started:
+		assert.Equal(t, "a", id)
+	case <-time.After(time.Second):
+		t.Fatal("leader not yielded to new registration")
+	}
+}
+
+func TestElection_ResignWithdrawsCandidate(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	store := NewMemoryStore()
+	started := make(chan string, 4)
+	b := newTestElection(t, ctx, "b", store.Elector("job"), started)
+	b.config.Priority = 1
+	b.config.ResignBackoff = time.Minute
+	go b.Run()
+	assert.Equal(t, "b", <-started)
+
+	// b Resign 后在 ResignBackoff 期间不再是候选成员, a 获取租约后不会放弃
+	a := newTestElection(t, ctx, "a", store.Elector("job"), started)
+	go a.Run()
+	assert.NoError(t, b.Resign(ctx))
+	assert.Equal(t, "a", <-started)
+	store.mu.Lock()
+	assert.Empty(t, store.candidates["job"])
+	store.mu.Unlock()
+	select {
+	case id := <-started:
+		t.Fatalf("%s should not lead", id)
+	case <-time.After(100 * time.Millisecond):
+	}
+	assert.True(t, a.IsLeader())
+}
+
+func TestElection_RequiresZkConfig(t *testing.T) {
+	_, err := NewElection(context.Background(), log.DefaultLogger(), &Config{})
+	assert.Error(t, err)
+	_, err = NewElection(context.Background(), log.DefaultLogger(), nil)
+	assert.Error(t, err)
+}
+
+func TestRedisElector(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+	ctx := context.Background()
+	opts := LeaseOptions{LeaseDuration: time.Second, RenewPeriod: 10 * time.Millisecond}
+	a := NewRedisElector(client, "election/job", opts)
+	b := NewRedisElector(client, "election/job", opts)
+
+	_, ok, err := b.Leader(ctx)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	ok, err = a.Acquire(ctx, LeaderInfo{Identity: "a", Address: "10.0.0.1:8080"})
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	ok, _ = a.Acquire(ctx, LeaderInfo{Identity: "a"})
+	assert.True(t, ok)
+	ok, err = b.Acquire(ctx, LeaderInfo{Identity: "b"})
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	leader, ok, err := b.Leader(ctx)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "a", leader.Identity)
+	assert.Equal(t, "10.0.0.1:8080", leader.Address)
+
+	assert.NoError(t, a.Release(ctx))
+	ok, _ = b.Acquire(ctx, LeaderInfo{})
+	assert.True(t, ok)
+
+	// 租约过期后被 a 获取, b 续约失败
+	mr.FastForward(2 * time.Second)
+	ok, _ = a.Acquire(ctx, LeaderInfo{})
+	assert.True(t, ok)
+	assert.ErrorIs(t, b.Hold(ctx), ErrLeaseLost)
+
+	holdCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	assert.NoError(t, a.Hold(holdCtx))
+	assert.NoError(t, a.Close())
+	assert.False(t, mr.Exists("election/job"))
+}
+
+func TestSQLElector(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "election.db")), &gorm.Config{Logger: logger.Discard})
+	assert.NoError(t, err)
+	ctx := context.Background()
+	opts := LeaseOptions{LeaseDuration: time.Minute, RenewPeriod: 10 * time.Millisecond}
+	a, err := NewSQLElector(db, "job", opts)
+	assert.NoError(t, err)
+	b, err := NewSQLElector(db, "job", opts)
+	assert.NoError(t, err)
+
+	ok, err := a.Acquire(ctx, LeaderInfo{})
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	ok, _ = a.Acquire(ctx, LeaderInfo{})
+	assert.True(t, ok)
+	ok, err = b.Acquire(ctx, LeaderInfo{})
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	// b 的时钟超过租约过期时间后接管, a 续约失败
+	b.(*sqlElector).now = func() time.Time { return time.Now().Add(2 * time.Minute) }
+	startedAt := time.UnixMilli(time.Now().UnixMilli())
+	ok, err = b.Acquire(ctx, LeaderInfo{Identity: "b", StartedAt: startedAt, Metadata: map[string]string{"zone": "az1"}})
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.ErrorIs(t, a.Hold(ctx), ErrLeaseLost)
+	leader, ok, err := a.Leader(ctx)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, LeaderInfo{Identity: "b", StartedAt: startedAt, Metadata: map[string]string{"zone": "az1"}}, leader)
+
+	var lease Lease
+	assert.NoError(t, db.First(&lease, "name = ?", "job").Error)
+	assert.Equal(t, int64(2), lease.Version)
+
+	assert.NoError(t, b.Close())
+	ok, _ = a.Acquire(ctx, LeaderInfo{})
+	assert.True(t, ok)
+}
@@ -0,0 +1,112 @@
+package election
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+const (
+	DefaultLeaseDuration = 15 * time.Second
+	DefaultRetryPeriod   = 2 * time.Second
+	DefaultStopTimeout   = 10 * time.Second
+)
+
+// ErrLeaseLost 续约失败或租约已被其他成员持有
+var ErrLeaseLost = errors.New("leader lease lost")
+
+// Elector 选主后端, 负责在存储中争抢和维持 leader 租约. 同一个 Elector 只由一个 Election 使用
+type Elector interface {
+	// Acquire 尝试获取 leader 租约并写入 leader 信息, 已被其他成员持有时返回 false.
+	// 租约仍由自己持有时不会更新 leader 信息
+	Acquire(ctx context.Context, leader LeaderInfo) (bool, error)
+	// Hold 在持有租约期间阻塞并续约, 租约丢失时返回错误, ctx 取消时返回 nil
+	Hold(ctx context.Context) error
+	// Release 释放持有的租约, 未持有时不做任何操作
+	Release(ctx context.Context) error
+	// Close 释放租约并关闭后端使用的连接
+	Close() error
+	// Token 返回最近一次获取租约时的 fencing token, 每次有新的 leader 时单调递增,
+	// 下游存储可以拒绝 token 小于已见过的最大值的写入, 以屏蔽失联后仍在工作的旧 leader
+	Token() int64
+	// Leader 返回当前 leader 的信息, 没有 leader 时返回 false
+	Leader(ctx context.Context) (LeaderInfo, bool, error)
+}
+
+// LeaderInfo 获取租约时写入存储的 leader 信息, 其他成员可以据此把请求转发给 leader
+type LeaderInfo struct {
+	Identity  string            `json:"identity"`
+	Address   string            `json:"address,omitempty"`
+	StartedAt time.Time         `json:"startedAt"` // 成为 leader 的时间
+	Metadata  map[string]string `json:"metadata,omitempty"`
+}
+
+// leaderRecord 保存在 zookeeper 节点或 redis key 中的内容, Holder 用于区分同一 Identity 的不同 Elector
+type leaderRecord struct {
+	Holder string `json:"holder"`
+	LeaderInfo
+}
+
+func encodeLeader(holder string, leader LeaderInfo) string {
+	data, _ := json.Marshal(leaderRecord{Holder: holder, LeaderInfo: leader})
+	return string(data)
+}
+
+// decodeLeader 解析 encodeLeader 的结果, 兼容旧版本只保存 holder 的内容
+func decodeLeader(data []byte) leaderRecord {
+	var record leaderRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return leaderRecord{Holder: string(data)}
+	}
+	return record
+}
+
+// LeaseOptions 基于租约的后端(redis、sql)的参数
+type LeaseOptions struct {
+	// LeaseDuration 租约时长, leader 失联超过该时间后其他成员才能获取租约, 默认 15s
+	LeaseDuration time.Duration
+	// RenewPeriod 续约间隔, 默认为 LeaseDuration 的 1/3
+	RenewPeriod time.Duration
+}
+
+func (o LeaseOptions) lease() time.Duration {
+	if o.LeaseDuration > 0 {
+		return o.LeaseDuration
+	}
+	return DefaultLeaseDuration
+}
+
+func (o LeaseOptions) renew() time.Duration {
+	if o.RenewPeriod > 0 {
+		return o.RenewPeriod
+	}
+	return o.lease() / 3
+}
+
+// hold 按 o 的续约间隔调用 renew, ctx 取消时返回 nil. renew 返回 ErrLeaseLost 时立即返回,
+// 其他错误(如网络抖动)会继续重试, 直到距离上次续约成功超过 LeaseDuration - RenewPeriod
+func hold(ctx context.Context, o LeaseOptions, renew func(ctx context.Context) error) error {
+	period := o.renew()
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+	deadline := time.Now().Add(o.lease() - period)
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			err := renew(ctx)
+			if ctx.Err() != nil {
+				return nil
+			}
+			if err == nil {
+				deadline = time.Now().Add(o.lease() - period)
+				continue
+			}
+			if errors.Is(err, ErrLeaseLost) || time.Now().After(deadline) {
+				return err
+			}
+		}
+	}
+}
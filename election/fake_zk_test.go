@@ -0,0 +1,375 @@
+package election
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/neura-flow/common/log"
+	"github.com/samuel/go-zookeeper/zk"
+)
+
+// fakeZk 内存中的 zookeeper, 多个 fakeConn 共享同一棵节点树
+type fakeZk struct {
+	mu       sync.Mutex
+	zxid     int64
+	session  int64
+	nodes    map[string]*fakeNode
+	watchers map[string][]fakeWatcher
+}
+
+type fakeNode struct {
+	data  []byte
+	stat  zk.Stat
+	owner *fakeConn
+	seq   int32
+}
+
+type fakeWatcher struct {
+	kind  string // exist、data、child
+	owner *fakeConn
+	ch    chan zk.Event
+}
+
+func newFakeZk() *fakeZk {
+	return &fakeZk{nodes: map[string]*fakeNode{"/": {}}, watchers: make(map[string][]fakeWatcher)}
+}
+
+// fakeConn 一个 zookeeper session, events 对应 zk.Connect 返回的 channel
+type fakeConn struct {
+	zk        *fakeZk
+	sessionID int64
+	state     zk.State
+	events    chan zk.Event
+	closed    bool
+}
+
+func (f *fakeZk) connect() *fakeConn {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.session++
+	return &fakeConn{zk: f, sessionID: f.session, state: zk.StateHasSession, events: make(chan zk.Event, 16)}
+}
+
+// lock 返回使用新 session 的 ResourceLock
+func (f *fakeZk) lock() (*ResourceLock, *fakeConn) {
+	c := f.connect()
+	return newResourceLock(log.DefaultLogger(), c, c.events, nil), c
+}
+
+func (c *fakeConn) sendSession(state zk.State) {
+	c.events <- zk.Event{Type: zk.EventSession, State: state}
+}
+
+// disconnect 模拟连接断开, session 仍然有效
+func (c *fakeConn) disconnect() {
+	c.zk.mu.Lock()
+	c.state = zk.StateDisconnected
+	c.zk.mu.Unlock()
+	c.sendSession(zk.StateDisconnected)
+}
+
+// reconnect 模拟在 session 超时前重新连接
+func (c *fakeConn) reconnect() {
+	c.zk.mu.Lock()
+	c.state = zk.StateHasSession
+	c.zk.mu.Unlock()
+	c.sendSession(zk.StateHasSession)
+}
+
+// newSession 模拟 session 过期后客户端使用新的 session 重新连接
+func (c *fakeConn) newSession() {
+	c.zk.mu.Lock()
+	c.zk.session++
+	c.sessionID = c.zk.session
+	c.state = zk.StateHasSession
+	c.zk.mu.Unlock()
+	c.sendSession(zk.StateHasSession)
+}
+
+// expire 模拟 session 过期, 删除 session 创建的临时节点并通知 session 的 watcher
+func (c *fakeConn) expire() {
+	f := c.zk
+	f.mu.Lock()
+	c.state = zk.StateExpired
+	f.removeSession(c, zk.ErrSessionExpired)
+	f.mu.Unlock()
+	c.sendSession(zk.StateExpired)
+}
+
+func (c *fakeConn) Close() {
+	f := c.zk
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if c.closed {
+		return
+	}
+	c.closed = true
+	c.state = zk.StateDisconnected
+	f.removeSession(c, zk.ErrClosing)
+	close(c.events)
+}
+
+// removeSession 先通知 c 的 watcher 不再监听, 再删除 c 创建的临时节点
+func (f *fakeZk) removeSession(c *fakeConn, reason error) {
+	for p, ws := range f.watchers {
+		kept := ws[:0]
+		for _, w := range ws {
+			if w.owner == c {
+				w.ch <- zk.Event{Type: zk.EventNotWatching, State: zk.StateDisconnected, Path: p, Err: reason}
+				close(w.ch)
+			} else {
+				kept = append(kept, w)
+			}
+		}
+		f.watchers[p] = kept
+	}
+	var ephemeral []string
+	for p, n := range f.nodes {
+		if n.owner == c {
+			ephemeral = append(ephemeral, p)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(ephemeral)))
+	for _, p := range ephemeral {
+		f.delete(p)
+	}
+}
+
+func (c *fakeConn) check() error {
+	if c.closed {
+		return zk.ErrClosing
+	}
+	if c.state != zk.StateHasSession {
+		return zk.ErrNoServer
+	}
+	return nil
+}
+
+// fire 触发 p 上 kinds 类型的 watcher, watcher 只触发一次
+func (f *fakeZk) fire(p string, typ zk.EventType, kinds ...string) {
+	kept := f.watchers[p][:0]
+	for _, w := range f.watchers[p] {
+		matched := false
+		for _, k := range kinds {
+			matched = matched || w.kind == k
+		}
+		if matched {
+			w.ch <- zk.Event{Type: typ, State: zk.StateHasSession, Path: p}
+			close(w.ch)
+		} else {
+			kept = append(kept, w)
+		}
+	}
+	f.watchers[p] = kept
+}
+
+func (f *fakeZk) watch(c *fakeConn, p, kind string) <-chan zk.Event {
+	ch := make(chan zk.Event, 1)
+	f.watchers[p] = append(f.watchers[p], fakeWatcher{kind: kind, owner: c, ch: ch})
+	return ch
+}
+
+// watching 返回 p 上的 watcher 数量
+func (f *fakeZk) watching(p string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.watchers[p])
+}
+
+func (f *fakeZk) delete(p string) {
+	delete(f.nodes, p)
+	f.zxid++
+	f.fire(p, zk.EventNodeDeleted, "exist", "data", "child")
+	parent := path.Dir(p)
+	if n, ok := f.nodes[parent]; ok {
+		n.stat.NumChildren--
+		n.stat.Cversion++
+		f.fire(parent, zk.EventNodeChildrenChanged, "child")
+	}
+}
+
+func (c *fakeConn) Create(p string, data []byte, flags int32, _ []zk.ACL) (string, error) {
+	f := c.zk
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := c.check(); err != nil {
+		return "", err
+	}
+	parent, ok := f.nodes[path.Dir(p)]
+	if !ok {
+		return "", zk.ErrNoNode
+	}
+	if flags&zk.FlagSequence != 0 {
+		p = fmt.Sprintf("%s%010d", p, parent.seq)
+		parent.seq++
+	}
+	if _, ok := f.nodes[p]; ok {
+		return "", zk.ErrNodeExists
+	}
+	f.zxid++
+	n := &fakeNode{data: data, stat: zk.Stat{Czxid: f.zxid, Mzxid: f.zxid, DataLength: int32(len(data))}}
+	if flags&zk.FlagEphemeral != 0 {
+		n.owner = c
+		n.stat.EphemeralOwner = c.sessionID
+	}
+	f.nodes[p] = n
+	parent.stat.NumChildren++
+	parent.stat.Cversion++
+	f.fire(p, zk.EventNodeCreated, "exist")
+	f.fire(path.Dir(p), zk.EventNodeChildrenChanged, "child")
+	return p, nil
+}
+
+func (c *fakeConn) Delete(p string, version int32) error {
+	f := c.zk
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := c.check(); err != nil {
+		return err
+	}
+	n, ok := f.nodes[p]
+	if !ok {
+		return zk.ErrNoNode
+	}
+	if version != -1 && version != n.stat.Version {
+		return zk.ErrBadVersion
+	}
+	if n.stat.NumChildren > 0 {
+		return zk.ErrNotEmpty
+	}
+	f.delete(p)
+	return nil
+}
+
+func (c *fakeConn) Exists(p string) (bool, *zk.Stat, error) {
+	c.zk.mu.Lock()
+	defer c.zk.mu.Unlock()
+	return c.exists(p)
+}
+
+func (c *fakeConn) exists(p string) (bool, *zk.Stat, error) {
+	f := c.zk
+	if err := c.check(); err != nil {
+		return false, nil, err
+	}
+	if n, ok := f.nodes[p]; ok {
+		stat := n.stat
+		return true, &stat, nil
+	}
+	return false, &zk.Stat{}, nil
+}
+
+func (c *fakeConn) ExistsW(p string) (bool, *zk.Stat, <-chan zk.Event, error) {
+	c.zk.mu.Lock()
+	defer c.zk.mu.Unlock()
+	exists, stat, err := c.exists(p)
+	if err != nil {
+		return false, nil, nil, err
+	}
+	return exists, stat, c.zk.watch(c, p, "exist"), nil
+}
+
+func (c *fakeConn) Get(p string) ([]byte, *zk.Stat, error) {
+	c.zk.mu.Lock()
+	defer c.zk.mu.Unlock()
+	return c.get(p)
+}
+
+func (c *fakeConn) get(p string) ([]byte, *zk.Stat, error) {
+	f := c.zk
+	if err := c.check(); err != nil {
+		return nil, nil, err
+	}
+	n, ok := f.nodes[p]
+	if !ok {
+		return nil, nil, zk.ErrNoNode
+	}
+	stat := n.stat
+	return n.data, &stat, nil
+}
+
+func (c *fakeConn) GetW(p string) ([]byte, *zk.Stat, <-chan zk.Event, error) {
+	c.zk.mu.Lock()
+	defer c.zk.mu.Unlock()
+	data, stat, err := c.get(p)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return data, stat, c.zk.watch(c, p, "data"), nil
+}
+
+func (c *fakeConn) Set(p string, data []byte, version int32) (*zk.Stat, error) {
+	f := c.zk
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := c.check(); err != nil {
+		return nil, err
+	}
+	n, ok := f.nodes[p]
+	if !ok {
+		return nil, zk.ErrNoNode
+	}
+	if version != -1 && version != n.stat.Version {
+		return nil, zk.ErrBadVersion
+	}
+	f.zxid++
+	n.data = data
+	n.stat.Version++
+	n.stat.Mzxid = f.zxid
+	n.stat.DataLength = int32(len(data))
+	f.fire(p, zk.EventNodeDataChanged, "exist", "data")
+	stat := n.stat
+	return &stat, nil
+}
+
+func (c *fakeConn) Children(p string) ([]string, *zk.Stat, error) {
+	c.zk.mu.Lock()
+	defer c.zk.mu.Unlock()
+	return c.children(p)
+}
+
+func (c *fakeConn) children(p string) ([]string, *zk.Stat, error) {
+	f := c.zk
+	if err := c.check(); err != nil {
+		return nil, nil, err
+	}
+	n, ok := f.nodes[p]
+	if !ok {
+		return nil, nil, zk.ErrNoNode
+	}
+	prefix := strings.TrimSuffix(p, "/") + "/"
+	var children []string
+	for child := range f.nodes {
+		if strings.HasPrefix(child, prefix) && !strings.Contains(child[len(prefix):], "/") {
+			children = append(children, child[len(prefix):])
+		}
+	}
+	sort.Strings(children)
+	stat := n.stat
+	return children, &stat, nil
+}
+
+func (c *fakeConn) ChildrenW(p string) ([]string, *zk.Stat, <-chan zk.Event, error) {
+	c.zk.mu.Lock()
+	defer c.zk.mu.Unlock()
+	children, stat, err := c.children(p)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return children, stat, c.zk.watch(c, p, "child"), nil
+}
+
+func (c *fakeConn) SessionID() int64 {
+	c.zk.mu.Lock()
+	defer c.zk.mu.Unlock()
+	return c.sessionID
+}
+
+func (c *fakeConn) State() zk.State {
+	c.zk.mu.Lock()
+	defer c.zk.mu.Unlock()
+	return c.state
+}
@@ -0,0 +1,153 @@
+package election
+
+import (
+	"context"
+	"strconv"
+	"sync"
+)
+
+// MemoryStore 进程内的选主后端, 用于测试, 同一个 MemoryStore 上相同名称的 Elector 互斥
+type MemoryStore struct {
+	mu     sync.Mutex
+	leases map[string]*memoryLease
+	tokens map[string]int64 // 每个名称最近一次发放的 fencing token
+	// candidates 每个名称的候选成员及其优先级
+	candidates map[string]map[*memoryElector]memoryCandidate
+	seq        int64 // 候选成员登记的序号
+}
+
+type memoryCandidate struct {
+	id       string // 每次登记生成新的 id
+	priority int
+}
+
+type memoryLease struct {
+	holder *memoryElector
+	leader LeaderInfo
+	lost   chan struct{} // 租约被 Revoke 或释放时关闭
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		leases:     make(map[string]*memoryLease),
+		tokens:     make(map[string]int64),
+		candidates: make(map[string]map[*memoryElector]memoryCandidate),
+	}
+}
+
+// Elector 返回竞争名称为 name 的租约的 Elector
+func (s *MemoryStore) Elector(name string) Elector {
+	return &memoryElector{store: s, name: name}
+}
+
+// Revoke 使 name 当前的租约失效, 模拟 leader 失联, 返回是否有成员持有租约
+func (s *MemoryStore) Revoke(name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	l, ok := s.leases[name]
+	if !ok {
+		return false
+	}
+	delete(s.leases, name)
+	close(l.lost)
+	return true
+}
+
+type memoryElector struct {
+	store *MemoryStore
+	name  string
+	token int64
+}
+
+func (e *memoryElector) Acquire(_ context.Context, leader LeaderInfo) (bool, error) {
+	s := e.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if l, ok := s.leases[e.name]; ok {
+		return l.holder == e, nil
+	}
+	s.tokens[e.name]++
+	e.token = s.tokens[e.name]
+	s.leases[e.name] = &memoryLease{holder: e, leader: leader, lost: make(chan struct{})}
+	return true, nil
+}
+
+func (e *memoryElector) Token() int64 {
+	e.store.mu.Lock()
+	defer e.store.mu.Unlock()
+	return e.token
+}
+
+func (e *memoryElector) Leader(context.Context) (LeaderInfo, bool, error) {
+	s := e.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if l, ok := s.leases[e.name]; ok {
+		return l.leader, true, nil
+	}
+	return LeaderInfo{}, false, nil
+}
+
+func (e *memoryElector) Hold(ctx context.Context) error {
+	s := e.store
+	s.mu.Lock()
+	l, ok := s.leases[e.name]
+	s.mu.Unlock()
+	if !ok || l.holder != e {
+		return ErrLeaseLost
+	}
+	select {
+	case <-ctx.Done():
+		return nil
+	case <-l.lost:
+		return ErrLeaseLost
+	}
+}
+
+func (e *memoryElector) Release(context.Context) error {
+	s := e.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if l, ok := s.leases[e.name]; ok && l.holder == e {
+		delete(s.leases, e.name)
+		close(l.lost)
+	}
+	return nil
+}
+
+func (e *memoryElector) higherCandidates(_ context.Context, _ string, priority int) ([]string, error) {
+	s := e.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if priority != 0 {
+		if s.candidates[e.name] == nil {
+			s.candidates[e.name] = make(map[*memoryElector]memoryCandidate)
+		}
+		c, ok := s.candidates[e.name][e]
+		if !ok {
+			s.seq++
+			c.id = strconv.FormatInt(s.seq, 10)
+		}
+		c.priority = priority
+		s.candidates[e.name][e] = c
+	}
+	var higher []string
+	for other, c := range s.candidates[e.name] {
+		if other != e && c.priority > priority {
+			higher = append(higher, c.id)
+		}
+	}
+	return higher, nil
+}
+
+func (e *memoryElector) withdrawCandidate(context.Context) error {
+	e.store.mu.Lock()
+	delete(e.store.candidates[e.name], e)
+	e.store.mu.Unlock()
+	return nil
+}
+
+func (e *memoryElector) Close() error {
+	_ = e.withdrawCandidate(context.Background())
+	return e.Release(context.Background())
+}
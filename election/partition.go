@@ -0,0 +1,434 @@
+package election
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/neura-flow/common/log"
+	"github.com/neura-flow/common/util"
+	"github.com/samuel/go-zookeeper/zk"
+)
+
+// PartitionConfig 分片选举配置, 所有成员使用相同的 ElectionRoot 和 Shards
+type PartitionConfig struct {
+	ZkServers    string
+	ElectionRoot string
+	Identity     string
+	// Shards 分片 ID, 不能包含 '/'
+	Shards []string
+	// SessionTimeout zookeeper session 超时时间, 默认 1s
+	SessionTimeout time.Duration
+	// SuspendGrace zookeeper 连接断开后保留分片的时间, 超过后停止所有分片, 默认为 SessionTimeout
+	SuspendGrace time.Duration
+	// RetryPeriod zookeeper 操作失败后重试的间隔, 默认 2s
+	RetryPeriod time.Duration
+	// StopTimeout 停止分片时等待 OnStartedShard 返回的时间, 超时后仍然释放分片, 默认 10s
+	StopTimeout time.Duration
+	Callbacks   PartitionCallbacks
+}
+
+type PartitionCallbacks struct {
+	// OnStartedShard 获得分片后执行, ctx 在失去分片时取消, 可以通过 FencingToken(ctx) 获取分片的 token
+	OnStartedShard func(ctx context.Context, shard string)
+	// OnStoppedShard OnStartedShard 返回或超过 StopTimeout 后执行
+	OnStoppedShard func(shard string)
+	// OnConnStateChange zookeeper 连接状态变化时调用
+	OnConnStateChange func(ConnState)
+}
+
+// PartitionManager 在存活的成员间分配分片, 每个分片同一时刻只由一个成员持有.
+// 成员注册为 ElectionRoot/members 下的临时顺序节点, 分片锁为 ElectionRoot/shards 下的临时节点,
+// 所有分片共享一个 zookeeper 连接. 成员加入或离开时按 assign 重新分配, 原持有者停止分片并删除分片锁后新的成员才会获得分片
+type PartitionManager struct {
+	ctx    context.Context
+	logger log.Logger
+	config *PartitionConfig
+	lock   *ResourceLock
+	member string // 自己的成员节点名称
+
+	mu    sync.Mutex
+	owned map[string]*ownedShard
+}
+
+type ownedShard struct {
+	cancel context.CancelFunc
+	done   chan struct{} // OnStartedShard 返回时关闭
+}
+
+// NewPartitionManager 连接 zookeeper 并创建 PartitionManager, 调用 Run 后开始参与分配
+func NewPartitionManager(ctx context.Context, logger log.Logger, cfg *PartitionConfig) (*PartitionManager, error) {
+	if err := validatePartition(cfg); err != nil {
+		return nil, err
+	}
+	lock, err := NewResourceLock(logger, cfg.ZkServers, WithSessionTimeout(cfg.SessionTimeout), WithStateListener(cfg.Callbacks.OnConnStateChange))
+	if err != nil {
+		return nil, err
+	}
+	return newPartitionManager(ctx, logger, cfg, lock), nil
+}
+
+func newPartitionManager(ctx context.Context, logger log.Logger, cfg *PartitionConfig, lock *ResourceLock) *PartitionManager {
+	return &PartitionManager{
+		ctx:    ctx,
+		logger: logger,
+		config: cfg,
+		lock:   lock,
+		owned:  make(map[string]*ownedShard),
+	}
+}
+
+func validatePartition(cfg *PartitionConfig) error {
+	if cfg == nil {
+		return errors.New("cfg is required")
+	}
+	if err := validateZk(&Config{ZkServers: cfg.ZkServers, ElectionRoot: cfg.ElectionRoot, ElectionID: "shards"}); err != nil {
+		return err
+	}
+	if len(cfg.Shards) == 0 {
+		return errors.New("shards is required")
+	}
+	seen := make(map[string]bool, len(cfg.Shards))
+	for _, shard := range cfg.Shards {
+		if util.IsBlank(&shard) || strings.Contains(shard, "/") {
+			return fmt.Errorf("invalid shard: %q", shard)
+		}
+		if seen[shard] {
+			return fmt.Errorf("duplicate shard: %s", shard)
+		}
+		seen[shard] = true
+	}
+	return nil
+}
+
+// Run 参与分片分配直到 ctx 取消, 返回前停止所有分片并关闭 zookeeper 连接
+func (m *PartitionManager) Run() {
+	defer m.close()
+	states, unsubscribe := m.lock.subscribe()
+	defer unsubscribe()
+
+	var (
+		grace     <-chan time.Time
+		suspended bool
+	)
+	for {
+		var changed <-chan struct{}
+		var retry <-chan time.Time
+		watchCtx, cancel := context.WithCancel(m.ctx)
+		if !suspended {
+			var err error
+			if changed, err = m.rebalance(watchCtx); err != nil {
+				m.logger.Debugf("%s failed to rebalance shards, err: %v", m.config.Identity, err)
+				retry = time.After(m.retryPeriod())
+			}
+		}
+		select {
+		case <-m.ctx.Done():
+			cancel()
+			return
+		case <-changed:
+		case <-retry:
+		case state := <-states:
+			switch state {
+			case ConnSuspended:
+				suspended = true
+				if grace == nil {
+					grace = time.After(m.suspendGrace())
+				}
+			case ConnConnected:
+				suspended = false
+				grace = nil
+			case ConnLost:
+				// 临时节点已被删除, 等待重新建立 session 后重新注册
+				suspended = true
+				grace = nil
+				m.stopAll()
+			}
+		case <-grace:
+			grace = nil
+			m.logger.Warnf("%s zookeeper connection suspended too long, stop all shards", m.config.Identity)
+			m.stopAll()
+		}
+		cancel()
+	}
+}
+
+// Shards 返回当前持有的分片
+func (m *PartitionManager) Shards() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	shards := make([]string, 0, len(m.owned))
+	for shard := range m.owned {
+		shards = append(shards, shard)
+	}
+	sort.Strings(shards)
+	return shards
+}
+
+// rebalance 按当前成员重新计算分配结果, 释放不再属于自己的分片并尝试获取分配给自己的分片.
+// 返回的 channel 在成员或相关分片锁变化时收到通知
+func (m *PartitionManager) rebalance(ctx context.Context) (<-chan struct{}, error) {
+	changed := make(chan struct{}, 1)
+	if err := m.register(); err != nil {
+		return nil, err
+	}
+	members, _, watch, err := m.lock.conn.ChildrenW(m.membersPath())
+	if err != nil {
+		return nil, err
+	}
+	m.notify(ctx, watch, changed)
+
+	assigned := make(map[string]bool)
+	for _, shard := range assign(m.config.Shards, members)[m.member] {
+		assigned[shard] = true
+	}
+	// 连接断开期间停止的分片可能仍持有分片锁, 因此检查所有未分配给自己的分片
+	for _, shard := range m.config.Shards {
+		if assigned[shard] {
+			continue
+		}
+		if err = m.release(shard); err != nil {
+			return nil, err
+		}
+	}
+	for _, shard := range m.config.Shards {
+		if !assigned[shard] {
+			continue
+		}
+		if err = m.acquire(ctx, shard, changed); err != nil {
+			return nil, err
+		}
+	}
+	return changed, nil
+}
+
+// acquire 尝试创建分片锁, 并监听分片锁的变化: 分片锁被其他成员持有时等待其删除, 自己持有的分片锁被删除时停止分片
+func (m *PartitionManager) acquire(ctx context.Context, shard string, changed chan<- struct{}) error {
+	shardPath := m.shardPath(shard)
+	_, owned := m.ownedShard(shard)
+	if !owned {
+		if _, err := m.lock.Create(shardPath, []byte(m.config.Identity), FlagEphemeral); err != nil && !errors.Is(err, zk.ErrNodeExists) {
+			return err
+		}
+	}
+	exists, stat, watch, err := m.lock.ExistsW(shardPath)
+	if err != nil {
+		return err
+	}
+	m.notify(ctx, watch, changed)
+	mine := exists && stat.EphemeralOwner == m.lock.conn.SessionID()
+	switch {
+	case mine && !owned:
+		m.logger.Infof("%s acquired shard %s", m.config.Identity, shard)
+		m.start(shard, stat.Czxid)
+	case !mine && owned:
+		m.logger.Warnf("%s lost shard %s", m.config.Identity, shard)
+		m.stop(shard)
+	}
+	return nil
+}
+
+// release 停止分片并删除自己持有的分片锁
+func (m *PartitionManager) release(shard string) error {
+	if _, ok := m.ownedShard(shard); ok {
+		m.logger.Infof("%s release shard %s", m.config.Identity, shard)
+		m.stop(shard)
+	}
+	shardPath := m.shardPath(shard)
+	_, stat, err := m.lock.conn.Exists(shardPath)
+	if err != nil {
+		return err
+	}
+	if stat == nil || stat.EphemeralOwner != m.lock.conn.SessionID() {
+		return nil
+	}
+	if err = m.lock.Delete(shardPath, stat.Version); errors.Is(err, zk.ErrNoNode) {
+		return nil
+	}
+	return err
+}
+
+func (m *PartitionManager) start(shard string, token int64) {
+	ctx, cancel := context.WithCancel(context.WithValue(m.ctx, tokenKey{}, token))
+	s := &ownedShard{cancel: cancel, done: make(chan struct{})}
+	m.mu.Lock()
+	m.owned[shard] = s
+	m.mu.Unlock()
+	go func() {
+		defer close(s.done)
+		if m.config.Callbacks.OnStartedShard != nil {
+			m.config.Callbacks.OnStartedShard(ctx, shard)
+		}
+	}()
+}
+
+// stop 取消分片的 ctx, 等待 OnStartedShard 返回后执行 OnStoppedShard
+func (m *PartitionManager) stop(shard string) {
+	s, ok := m.ownedShard(shard)
+	if !ok {
+		return
+	}
+	s.cancel()
+	timeout := m.config.StopTimeout
+	if timeout <= 0 {
+		timeout = DefaultStopTimeout
+	}
+	select {
+	case <-s.done:
+	case <-time.After(timeout):
+		m.logger.Warnf("%s shard %s not stopped in %s", m.config.Identity, shard, timeout)
+	}
+	m.mu.Lock()
+	delete(m.owned, shard)
+	m.mu.Unlock()
+	if m.config.Callbacks.OnStoppedShard != nil {
+		m.config.Callbacks.OnStoppedShard(shard)
+	}
+}
+
+func (m *PartitionManager) stopAll() {
+	for _, shard := range m.Shards() {
+		m.stop(shard)
+	}
+}
+
+func (m *PartitionManager) ownedShard(shard string) (*ownedShard, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, ok := m.owned[shard]
+	return s, ok
+}
+
+// close 停止所有分片后关闭连接, 成员节点和分片锁随 session 一起删除
+func (m *PartitionManager) close() {
+	m.stopAll()
+	m.lock.Close()
+}
+
+// register 确保自己的成员节点存在, session 过期后重新注册
+func (m *PartitionManager) register() error {
+	if m.member != "" {
+		_, stat, err := m.lock.conn.Exists(path.Join(m.membersPath(), m.member))
+		if err != nil {
+			return err
+		}
+		if stat != nil && stat.EphemeralOwner == m.lock.conn.SessionID() {
+			return nil
+		}
+	}
+	for _, p := range []string{m.config.ElectionRoot, m.membersPath(), m.shardsPath()} {
+		if err := ensurePath(m.lock, p); err != nil {
+			return err
+		}
+	}
+	created, err := m.lock.Create(m.membersPath()+"/member-", []byte(m.config.Identity), FlagEphemeralSequence)
+	if err != nil {
+		return err
+	}
+	m.member = path.Base(created)
+	m.logger.Infof("%s registered as %s", m.config.Identity, m.member)
+	return nil
+}
+
+// notify watch 触发时向 changed 发送通知, ctx 取消后不再等待
+func (m *PartitionManager) notify(ctx context.Context, watch <-chan zk.Event, changed chan<- struct{}) {
+	go func() {
+		select {
+		case <-watch:
+		case <-ctx.Done():
+			return
+		}
+		select {
+		case changed <- struct{}{}:
+		default:
+		}
+	}()
+}
+
+func (m *PartitionManager) retryPeriod() time.Duration {
+	if m.config.RetryPeriod > 0 {
+		return m.config.RetryPeriod
+	}
+	return DefaultRetryPeriod
+}
+
+func (m *PartitionManager) suspendGrace() time.Duration {
+	if m.config.SuspendGrace > 0 {
+		return m.config.SuspendGrace
+	}
+	if m.config.SessionTimeout > 0 {
+		return m.config.SessionTimeout
+	}
+	return DefaultSessionTimeout
+}
+
+func (m *PartitionManager) membersPath() string {
+	return path.Join(m.config.ElectionRoot, "members")
+}
+
+func (m *PartitionManager) shardsPath() string {
+	return path.Join(m.config.ElectionRoot, "shards")
+}
+
+func (m *PartitionManager) shardPath(shard string) string {
+	return path.Join(m.shardsPath(), shard)
+}
+
+// ensurePath 逐级创建 p 的永久节点
+func ensurePath(lock *ResourceLock, p string) error {
+	current := ""
+	for _, part := range strings.Split(strings.Trim(p, "/"), "/") {
+		current += "/" + part
+		exists, err := lock.Exists(current)
+		if err != nil {
+			return err
+		}
+		if exists {
+			continue
+		}
+		if _, err = lock.Create(current, nil, FlagPermanent); err != nil && !errors.Is(err, zk.ErrNodeExists) {
+			return err
+		}
+	}
+	return nil
+}
+
+// assign 使用有容量上限的 rendezvous hashing 把 shards 分配给 members: 每个分片按 hash 排序依次选择成员,
+// 每个成员最多分配 ceil(len(shards)/len(members)) 个分片. 所有成员对同样的输入得到同样的结果,
+// 成员变化时大部分分片保持不变
+func assign(shards, members []string) map[string][]string {
+	result := make(map[string][]string, len(members))
+	if len(members) == 0 {
+		return result
+	}
+	capacity := (len(shards) + len(members) - 1) / len(members)
+	for _, shard := range shards {
+		ranked := append([]string(nil), members...)
+		sort.Slice(ranked, func(i, j int) bool {
+			hi, hj := rendezvousHash(shard, ranked[i]), rendezvousHash(shard, ranked[j])
+			if hi != hj {
+				return hi > hj
+			}
+			return ranked[i] < ranked[j]
+		})
+		for _, member := range ranked {
+			if len(result[member]) < capacity {
+				result[member] = append(result[member], shard)
+				break
+			}
+		}
+	}
+	return result
+}
+
+func rendezvousHash(shard, member string) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(shard + "/" + member))
+	return h.Sum64()
+}
@@ -0,0 +1,176 @@
+package election
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/neura-flow/common/log"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAssign(t *testing.T) {
+	shards := make([]string, 10)
+	for i := range shards {
+		shards[i] = fmt.Sprintf("shard-%d", i)
+	}
+	members := []string{"member-0000000000", "member-0000000001", "member-0000000002"}
+	result := assign(shards, members)
+	total := 0
+	for _, member := range members {
+		assert.LessOrEqual(t, len(result[member]), 4)
+		total += len(result[member])
+	}
+	assert.Equal(t, len(shards), total)
+	// 成员顺序不影响结果
+	assert.Equal(t, result, assign(shards, []string{members[2], members[0], members[1]}))
+
+	// 成员离开后只移动离开的成员的分片
+	left := assign(shards, members[:2])
+	for _, member := range members[:2] {
+		for _, shard := range result[member] {
+			assert.Contains(t, left[member], shard)
+		}
+	}
+	assert.Empty(t, assign(shards, nil))
+}
+
+func TestValidatePartition(t *testing.T) {
+	cfg := &PartitionConfig{ZkServers: "127.0.0.1:2181", ElectionRoot: "/jobs", Shards: []string{"a", "b"}}
+	assert.NoError(t, validatePartition(cfg))
+	assert.Error(t, validatePartition(nil))
+	assert.Error(t, validatePartition(&PartitionConfig{ZkServers: "127.0.0.1:2181", ElectionRoot: "/jobs"}))
+	assert.Error(t, validatePartition(&PartitionConfig{ZkServers: "127.0.0.1:2181", ElectionRoot: "/jobs", Shards: []string{"a", "a"}}))
+	assert.Error(t, validatePartition(&PartitionConfig{ZkServers: "127.0.0.1:2181", ElectionRoot: "/jobs", Shards: []string{"a/b"}}))
+}
+
+// shardTracker 记录每个分片当前的持有者, 同一分片被两个成员同时持有时记录冲突
+type shardTracker struct {
+	mu        sync.Mutex
+	active    map[string]string
+	conflicts []string
+}
+
+func (s *shardTracker) callbacks(identity string) PartitionCallbacks {
+	return PartitionCallbacks{
+		OnStartedShard: func(ctx context.Context, shard string) {
+			s.mu.Lock()
+			if owner, ok := s.active[shard]; ok {
+				s.conflicts = append(s.conflicts, fmt.Sprintf("%s: %s and %s", shard, owner, identity))
+			}
+			s.active[shard] = identity
+			s.mu.Unlock()
+			<-ctx.Done()
+			s.mu.Lock()
+			delete(s.active, shard)
+			s.mu.Unlock()
+		},
+	}
+}
+
+func (s *shardTracker) owners() map[string][]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	owners := make(map[string][]string)
+	for shard, identity := range s.active {
+		owners[identity] = append(owners[identity], shard)
+	}
+	for _, shards := range owners {
+		sort.Strings(shards)
+	}
+	return owners
+}
+
+func TestPartitionManager(t *testing.T) {
+	f := newFakeZk()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	shards := []string{"s0", "s1", "s2", "s3"}
+	tracker := &shardTracker{active: make(map[string]string)}
+	newManager := func(ctx context.Context, identity string) (*PartitionManager, <-chan struct{}) {
+		lock, _ := f.lock()
+		m := newPartitionManager(ctx, log.DefaultLogger(), &PartitionConfig{
+			ElectionRoot: "/jobs/order",
+			Identity:     identity,
+			Shards:       shards,
+			RetryPeriod:  10 * time.Millisecond,
+			Callbacks:    tracker.callbacks(identity),
+		}, lock)
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			m.Run()
+		}()
+		return m, done
+	}
+	waitOwners := func(want map[string]int) {
+		assert.Eventually(t, func() bool {
+			owners := tracker.owners()
+			if len(owners) != len(want) {
+				return false
+			}
+			for identity, n := range want {
+				if len(owners[identity]) != n {
+					return false
+				}
+			}
+			return true
+		}, time.Second, 5*time.Millisecond, "want %v", want)
+	}
+
+	a, _ := newManager(ctx, "a")
+	waitOwners(map[string]int{"a": 4})
+	assert.Equal(t, shards, a.Shards())
+
+	ctxB, cancelB := context.WithCancel(ctx)
+	b, doneB := newManager(ctxB, "b")
+	waitOwners(map[string]int{"a": 2, "b": 2})
+	assert.ElementsMatch(t, shards, append(a.Shards(), b.Shards()...))
+
+	// b 离开后 a 接管所有分片
+	cancelB()
+	<-doneB
+	assert.Empty(t, b.Shards())
+	waitOwners(map[string]int{"a": 4})
+	tracker.mu.Lock()
+	assert.Empty(t, tracker.conflicts)
+	tracker.mu.Unlock()
+}
+
+func TestPartitionManager_SessionExpired(t *testing.T) {
+	f := newFakeZk()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	tokens := make(chan int64, 4)
+	lock, conn := f.lock()
+	m := newPartitionManager(ctx, log.DefaultLogger(), &PartitionConfig{
+		ElectionRoot: "/jobs",
+		Identity:     "a",
+		Shards:       []string{"s0"},
+		RetryPeriod:  10 * time.Millisecond,
+		Callbacks: PartitionCallbacks{
+			OnStartedShard: func(ctx context.Context, shard string) {
+				token, _ := FencingToken(ctx)
+				tokens <- token
+				<-ctx.Done()
+			},
+		},
+	}, lock)
+	go m.Run()
+	first := <-tokens
+
+	// session 过期后停止分片, 重新连接(模拟新的 session)后重新注册并获得分片
+	conn.disconnect()
+	conn.expire()
+	assert.Eventually(t, func() bool { return len(m.Shards()) == 0 }, time.Second, 5*time.Millisecond)
+	conn.newSession()
+	select {
+	case token := <-tokens:
+		assert.Greater(t, token, first)
+	case <-time.After(time.Second):
+		t.Fatal("shard not reacquired after new session")
+	}
+}
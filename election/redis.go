@@ -0,0 +1,124 @@
+package election
+
+import (
+	"context"
+	"sync"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/neura-flow/common/util"
+)
+
+var (
+	// acquireScript key 不存在时设置为 ARGV[1] 并返回递增后的 KEYS[2], key 已为 ARGV[3] 时续约并返回 -1, 否则返回 0
+	acquireScript = redis.NewScript(`
+if redis.call("SET", KEYS[1], ARGV[1], "NX", "PX", ARGV[2]) then
+	return redis.call("INCR", KEYS[2])
+end
+if redis.call("GET", KEYS[1]) == ARGV[3] then
+	redis.call("PEXPIRE", KEYS[1], ARGV[2])
+	return -1
+end
+return 0`)
+	// renewScript key 的值为 ARGV[1] 时续约 ARGV[2] 毫秒
+	renewScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0`)
+	// releaseScript key 的值为 ARGV[1] 时删除 key
+	releaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+)
+
+// redisElector 使用 SET key value NX PX 获取租约, 定期通过 lua 脚本续约. value 为 holder 和 leader 信息的 json.
+// fencing token 保存在 key:token 中, 每次获取租约时加 1
+type redisElector struct {
+	client redis.UniversalClient
+	key    string
+	holder string
+	opts   LeaseOptions
+
+	mu    sync.Mutex
+	value string // 最近一次获取租约时写入的 value
+	token int64
+}
+
+// NewRedisElector 返回使用 redis key 作为租约的 Elector, client 一般为 client/redis.Client, 例如:
+//
+//	cli, _ := redis.NewClient(ctx, logger, redisCfg)
+//	elector := election.NewRedisElector(cli, "election/order-job", election.LeaseOptions{})
+//
+// 租约在 leader 失联 LeaseDuration 后过期, Close 不会关闭 client.
+// 集群模式下 key 和 key:token 需要在同一个 slot, 可以使用 hash tag, 例如 "{election/order-job}"
+func NewRedisElector(client redis.UniversalClient, key string, opts LeaseOptions) Elector {
+	return &redisElector{
+		client: client,
+		key:    key,
+		holder: util.GUID(),
+		opts:   opts,
+	}
+}
+
+func (e *redisElector) Acquire(ctx context.Context, leader LeaderInfo) (bool, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	// 租约可能仍由自己持有, 例如上次续约失败后重新参与选举, 此时 value 和 token 不变
+	value := encodeLeader(e.holder, leader)
+	n, err := acquireScript.Run(ctx, e.client, []string{e.key, e.key + ":token"}, value, e.opts.lease().Milliseconds(), e.value).Int64()
+	if err != nil || n == 0 {
+		return false, err
+	}
+	if n > 0 {
+		e.value = value
+		e.token = n
+	}
+	return true, nil
+}
+
+func (e *redisElector) Token() int64 {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.token
+}
+
+func (e *redisElector) Leader(ctx context.Context) (LeaderInfo, bool, error) {
+	data, err := e.client.Get(ctx, e.key).Bytes()
+	if err == redis.Nil {
+		return LeaderInfo{}, false, nil
+	} else if err != nil {
+		return LeaderInfo{}, false, err
+	}
+	return decodeLeader(data).LeaderInfo, true, nil
+}
+
+func (e *redisElector) current() string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.value
+}
+
+func (e *redisElector) renew(ctx context.Context) error {
+	n, err := renewScript.Run(ctx, e.client, []string{e.key}, e.current(), e.opts.lease().Milliseconds()).Int()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrLeaseLost
+	}
+	return nil
+}
+
+func (e *redisElector) Hold(ctx context.Context) error {
+	return hold(ctx, e.opts, e.renew)
+}
+
+func (e *redisElector) Release(ctx context.Context) error {
+	return releaseScript.Run(ctx, e.client, []string{e.key}, e.current()).Err()
+}
+
+func (e *redisElector) Close() error {
+	return e.Release(context.Background())
+}
@@ -0,0 +1,176 @@
+package election
+
+import (
+	"context"
+	"encoding/json"
+	"sync/atomic"
+	"time"
+
+	"github.com/neura-flow/common/util"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Lease sql 后端使用的租约表, 每个选举一行
+type Lease struct {
+	Name     string `gorm:"primaryKey;size:191"`
+	Holder   string `gorm:"size:64;not null"`
+	ExpireAt int64  `gorm:"not null"` // 过期时间, unix 毫秒
+	Version  int64  `gorm:"not null"` // 每次有新的 leader 时加 1
+	Identity string `gorm:"size:191"`
+	Address  string `gorm:"size:255"`
+	// StartedAt 成为 leader 的时间, unix 毫秒
+	StartedAt int64
+	Metadata  string `gorm:"type:text"` // json
+}
+
+func newLease(name, holder string, expireAt int64, leader LeaderInfo) *Lease {
+	lease := &Lease{
+		Name:      name,
+		Holder:    holder,
+		ExpireAt:  expireAt,
+		Version:   1,
+		Identity:  leader.Identity,
+		Address:   leader.Address,
+		StartedAt: leader.StartedAt.UnixMilli(),
+	}
+	if len(leader.Metadata) > 0 {
+		data, _ := json.Marshal(leader.Metadata)
+		lease.Metadata = string(data)
+	}
+	return lease
+}
+
+// LeaderInfo 返回租约中的 leader 信息
+func (l *Lease) LeaderInfo() LeaderInfo {
+	info := LeaderInfo{Identity: l.Identity, Address: l.Address, StartedAt: time.UnixMilli(l.StartedAt)}
+	if l.Metadata != "" {
+		_ = json.Unmarshal([]byte(l.Metadata), &info.Metadata)
+	}
+	return info
+}
+
+func (Lease) TableName() string {
+	return "election_leases"
+}
+
+// sqlElector 使用数据库中的一行作为租约, 通过条件更新保证同一时刻只有一个成员持有
+type sqlElector struct {
+	db     *gorm.DB
+	name   string
+	holder string
+	opts   LeaseOptions
+	now    func() time.Time
+	token  int64
+}
+
+// NewSQLElector 返回使用 election_leases 表中 name 对应行作为租约的 Elector, 不存在时自动建表.
+// db 一般为 client/mysql.Client 或 client/sqlite.Client 的 DB, 例如:
+//
+//	cli, _ := mysql.NewClient(ctx, logger, mysqlCfg)
+//	elector, err := election.NewSQLElector(cli.DB, "order-job", election.LeaseOptions{})
+//
+// 租约过期时间基于各成员的本地时钟, 成员间的时钟偏差需要远小于 LeaseDuration. Close 不会关闭 db
+func NewSQLElector(db *gorm.DB, name string, opts LeaseOptions) (Elector, error) {
+	if err := db.AutoMigrate(&Lease{}); err != nil {
+		return nil, err
+	}
+	return &sqlElector{
+		db:     db,
+		name:   name,
+		holder: util.GUID(),
+		opts:   opts,
+		now:    time.Now,
+	}, nil
+}
+
+func (e *sqlElector) expireAt() int64 {
+	return e.now().Add(e.opts.lease()).UnixMilli()
+}
+
+func (e *sqlElector) Acquire(ctx context.Context, leader LeaderInfo) (bool, error) {
+	db := e.db.WithContext(ctx)
+	lease := newLease(e.name, e.holder, e.expireAt(), leader)
+	res := db.Clauses(clause.OnConflict{DoNothing: true}).Create(lease)
+	if res.Error != nil {
+		return false, res.Error
+	}
+	if res.RowsAffected == 1 {
+		atomic.StoreInt64(&e.token, 1)
+		return true, nil
+	}
+	// 租约可能仍由自己持有, 例如上次续约失败后重新参与选举
+	if err := e.renew(ctx); err != ErrLeaseLost {
+		if err != nil {
+			return false, err
+		}
+		return true, e.loadToken(ctx)
+	}
+	// 接管已过期的租约
+	res = db.Model(&Lease{}).
+		Where("name = ? AND expire_at < ?", e.name, e.now().UnixMilli()).
+		Updates(map[string]interface{}{
+			"holder":     e.holder,
+			"expire_at":  lease.ExpireAt,
+			"version":    gorm.Expr("version + 1"),
+			"identity":   lease.Identity,
+			"address":    lease.Address,
+			"started_at": lease.StartedAt,
+			"metadata":   lease.Metadata,
+		})
+	if res.Error != nil || res.RowsAffected == 0 {
+		return false, res.Error
+	}
+	return true, e.loadToken(ctx)
+}
+
+// loadToken 读取租约的 Version 作为 fencing token
+func (e *sqlElector) loadToken(ctx context.Context) error {
+	var lease Lease
+	if err := e.db.WithContext(ctx).Select("version").Where("name = ?", e.name).Take(&lease).Error; err != nil {
+		return err
+	}
+	atomic.StoreInt64(&e.token, lease.Version)
+	return nil
+}
+
+func (e *sqlElector) Leader(ctx context.Context) (LeaderInfo, bool, error) {
+	var leases []Lease
+	err := e.db.WithContext(ctx).Where("name = ? AND expire_at >= ?", e.name, e.now().UnixMilli()).Limit(1).Find(&leases).Error
+	if err != nil || len(leases) == 0 {
+		return LeaderInfo{}, false, err
+	}
+	return leases[0].LeaderInfo(), true, nil
+}
+
+// Token 返回租约的 Version
+func (e *sqlElector) Token() int64 {
+	return atomic.LoadInt64(&e.token)
+}
+
+func (e *sqlElector) renew(ctx context.Context) error {
+	res := e.db.WithContext(ctx).Model(&Lease{}).
+		Where("name = ? AND holder = ?", e.name, e.holder).
+		Update("expire_at", e.expireAt())
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrLeaseLost
+	}
+	return nil
+}
+
+func (e *sqlElector) Hold(ctx context.Context) error {
+	return hold(ctx, e.opts, e.renew)
+}
+
+func (e *sqlElector) Release(ctx context.Context) error {
+	return e.db.WithContext(ctx).Model(&Lease{}).
+		Where("name = ? AND holder = ?", e.name, e.holder).
+		Update("expire_at", 0).Error
+}
+
+func (e *sqlElector) Close() error {
+	return e.Release(context.Background())
+}
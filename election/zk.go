@@ -0,0 +1,590 @@
+package election
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/neura-flow/common/log"
+	"github.com/neura-flow/common/util"
+	"github.com/samuel/go-zookeeper/zk"
+)
+
+const DefaultSessionTimeout = time.Second
+
+// ConnState zookeeper 连接状态
+type ConnState int32
+
+const (
+	ConnConnected ConnState = iota // 已连接且 session 有效
+	ConnSuspended                  // 连接断开, session 可能仍然有效, 临时节点暂时保留
+	ConnLost                       // session 已过期, 临时节点已被删除
+)
+
+func (s ConnState) String() string {
+	switch s {
+	case ConnConnected:
+		return "connected"
+	case ConnSuspended:
+		return "suspended"
+	case ConnLost:
+		return "lost"
+	default:
+		return fmt.Sprintf("ConnState(%d)", int32(s))
+	}
+}
+
+// errSuspendTimeout 连接断开超过 SuspendGrace 仍未恢复
+var errSuspendTimeout = fmt.Errorf("%w: zookeeper connection suspended too long", ErrLeaseLost)
+
+// zkElector 使用 ElectionRoot/ElectionID 临时节点作为租约, 节点内容为 resourceId 和 leader 信息的 json,
+// resourceId 用于判断当前的连接是否选主成功的连接.
+// fencing token 为节点创建时的 zxid
+type zkElector struct {
+	logger     log.Logger
+	config     *Config
+	lock       *ResourceLock
+	resourceId string
+
+	mu        sync.Mutex
+	token     int64
+	candidate string // 候选成员节点路径
+}
+
+// NewZkElector 返回基于 zookeeper 临时节点的 Elector, 使用 cfg 中的 ZkServers、ElectionRoot、ElectionID、SessionTimeout.
+// 连接断开时 leader 进入 suspended 状态, 超过 SuspendGrace 仍未恢复时放弃 leader; session 过期时立即放弃 leader
+func NewZkElector(logger log.Logger, cfg *Config) (Elector, error) {
+	if err := validateZk(cfg); err != nil {
+		return nil, err
+	}
+	lock, err := NewResourceLock(logger, cfg.ZkServers, WithSessionTimeout(cfg.SessionTimeout), WithStateListener(cfg.Callbacks.OnConnStateChange))
+	if err != nil {
+		return nil, err
+	}
+	return newZkElector(logger, cfg, lock), nil
+}
+
+func newZkElector(logger log.Logger, cfg *Config, lock *ResourceLock) *zkElector {
+	return &zkElector{
+		logger:     logger,
+		config:     cfg,
+		lock:       lock,
+		resourceId: util.GUID(),
+	}
+}
+
+func validateZk(cfg *Config) error {
+	if util.IsBlank(&cfg.ZkServers) {
+		return errors.New("zk servers is required")
+	}
+	if util.IsBlank(&cfg.ElectionRoot) {
+		return errors.New("root path is required")
+	}
+	if !strings.HasPrefix(cfg.ElectionRoot, "/") {
+		return errors.New("root path should begin with '/'")
+	}
+	if util.IsBlank(&cfg.ElectionID) {
+		return errors.New("leaderElectionID is required")
+	}
+	return nil
+}
+
+func (le *zkElector) Acquire(_ context.Context, leader LeaderInfo) (bool, error) {
+	if err := le.ensureRoot(); err != nil {
+		return false, err
+	}
+	fullElectionID := le.getFullElectionID()
+	data, stat, err := le.lock.conn.Get(fullElectionID)
+	if errors.Is(err, zk.ErrNoNode) {
+		created, err := le.lock.Create(fullElectionID, []byte(encodeLeader(le.resourceId, leader)), FlagEphemeral)
+		if errors.Is(err, zk.ErrNodeExists) {
+			return false, nil
+		} else if err != nil {
+			return false, err
+		}
+		if !strings.EqualFold(created, fullElectionID) {
+			return false, fmt.Errorf("created node mismatch, want: %s created: %s", fullElectionID, created)
+		}
+		if data, stat, err = le.lock.conn.Get(fullElectionID); err != nil {
+			return false, err
+		}
+	} else if err != nil {
+		return false, err
+	}
+	if !strings.EqualFold(decodeLeader(data).Holder, le.resourceId) {
+		return false, nil
+	}
+	le.mu.Lock()
+	le.token = stat.Czxid
+	le.mu.Unlock()
+	return true, nil
+}
+
+func (le *zkElector) Token() int64 {
+	le.mu.Lock()
+	defer le.mu.Unlock()
+	return le.token
+}
+
+func (le *zkElector) Leader(context.Context) (LeaderInfo, bool, error) {
+	data, _, err := le.lock.conn.Get(le.getFullElectionID())
+	if errors.Is(err, zk.ErrNoNode) {
+		return LeaderInfo{}, false, nil
+	} else if err != nil {
+		return LeaderInfo{}, false, err
+	}
+	return decodeLeader(data).LeaderInfo, true, nil
+}
+
+// watchLeader 通过 GetW 监听 leader 节点, 节点不存在时通过 ExistsW 监听节点创建
+func (le *zkElector) watchLeader(ctx context.Context) (LeaderInfo, bool, <-chan struct{}, error) {
+	path := le.getFullElectionID()
+	data, _, watch, err := le.lock.conn.GetW(path)
+	found := err == nil
+	if errors.Is(err, zk.ErrNoNode) {
+		var exists bool
+		if exists, _, watch, err = le.lock.ExistsW(path); err != nil {
+			return LeaderInfo{}, false, nil, err
+		}
+		if exists {
+			// 节点在 GetW 和 ExistsW 之间被创建, 立即重新获取
+			changed := make(chan struct{})
+			close(changed)
+			return LeaderInfo{}, false, changed, nil
+		}
+	} else if err != nil {
+		return LeaderInfo{}, false, nil, err
+	}
+	changed := make(chan struct{})
+	go func() {
+		select {
+		case <-watch:
+		case <-ctx.Done():
+		}
+		close(changed)
+	}()
+	if !found {
+		return LeaderInfo{}, false, changed, nil
+	}
+	return decodeLeader(data).LeaderInfo, true, changed, nil
+}
+
+// Hold 通过 ExistsW 监听 leader 节点, 节点被删除或不再属于当前 session 时返回 ErrLeaseLost,
+// 连接断开超过 SuspendGrace 或 session 过期时同样返回
+func (le *zkElector) Hold(ctx context.Context) error {
+	states, unsubscribe := le.lock.subscribe()
+	defer unsubscribe()
+
+	path := le.getFullElectionID()
+	watch, err := le.watchOwned(path)
+	if err != nil {
+		return err
+	}
+	var grace <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			le.logger.Infof("%s receive cancel", le.config.Identity)
+			return nil
+		case event := <-watch:
+			switch event.Type {
+			case zk.EventNodeDeleted:
+				return ErrLeaseLost
+			case zk.EventNotWatching:
+				return fmt.Errorf("%w: %v", ErrLeaseLost, event.Err)
+			}
+			if watch, err = le.watchOwned(path); err != nil {
+				return err
+			}
+		case state := <-states:
+			switch state {
+			case ConnSuspended:
+				if grace == nil {
+					le.logger.Warnf("%s zookeeper connection suspended, give up leadership in %s", le.config.Identity, le.suspendGrace())
+					grace = time.After(le.suspendGrace())
+				}
+			case ConnConnected:
+				if grace != nil {
+					le.logger.Infof("%s zookeeper connection resumed", le.config.Identity)
+					grace = nil
+					// 断开期间节点可能已被删除, 重新检查
+					if watch, err = le.watchOwned(path); err != nil {
+						return err
+					}
+				}
+			case ConnLost:
+				return fmt.Errorf("%w: %v", ErrLeaseLost, zk.ErrSessionExpired)
+			}
+		case <-grace:
+			return errSuspendTimeout
+		}
+	}
+}
+
+// watchOwned 通过 ExistsW 监听 path, path 不存在或不是当前 session 创建的节点时返回 ErrLeaseLost
+func (le *zkElector) watchOwned(path string) (<-chan zk.Event, error) {
+	exists, stat, watch, err := le.lock.ExistsW(path)
+	if err != nil {
+		return nil, err
+	}
+	if !exists || stat.EphemeralOwner != le.lock.conn.SessionID() {
+		return nil, ErrLeaseLost
+	}
+	return watch, nil
+}
+
+func (le *zkElector) suspendGrace() time.Duration {
+	if le.config.SuspendGrace > 0 {
+		return le.config.SuspendGrace
+	}
+	if le.config.SessionTimeout > 0 {
+		return le.config.SessionTimeout
+	}
+	return DefaultSessionTimeout
+}
+
+// higherCandidates 候选成员注册为 ElectionRoot/ElectionID-candidates 下的临时顺序节点, 节点内容为 identity 和 priority,
+// 返回优先级更高的候选成员的节点路径
+func (le *zkElector) higherCandidates(_ context.Context, identity string, priority int) ([]string, error) {
+	root := le.getFullElectionID() + "-candidates"
+	if priority != 0 {
+		if err := le.registerCandidate(root, identity, priority); err != nil {
+			return nil, err
+		}
+	}
+	children, _, err := le.lock.conn.Children(root)
+	if errors.Is(err, zk.ErrNoNode) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	le.mu.Lock()
+	own := le.candidate
+	le.mu.Unlock()
+	var higher []string
+	for _, child := range children {
+		p := root + "/" + child
+		if p == own {
+			continue
+		}
+		data, _, err := le.lock.conn.Get(p)
+		if errors.Is(err, zk.ErrNoNode) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		var c candidate
+		if json.Unmarshal(data, &c) == nil && c.Priority > priority {
+			higher = append(higher, p)
+		}
+	}
+	return higher, nil
+}
+
+// withdrawCandidate 删除自己的候选成员节点
+func (le *zkElector) withdrawCandidate(context.Context) error {
+	le.mu.Lock()
+	own := le.candidate
+	le.candidate = ""
+	le.mu.Unlock()
+	if own == "" {
+		return nil
+	}
+	if err := le.lock.Delete(own, -1); err != nil && !errors.Is(err, zk.ErrNoNode) {
+		return err
+	}
+	return nil
+}
+
+type candidate struct {
+	Identity string `json:"identity"`
+	Priority int    `json:"priority"`
+}
+
+// registerCandidate 候选成员节点不存在(例如 session 过期)时重新注册
+func (le *zkElector) registerCandidate(root, identity string, priority int) error {
+	le.mu.Lock()
+	own := le.candidate
+	le.mu.Unlock()
+	if own != "" {
+		_, stat, err := le.lock.conn.Exists(own)
+		if err != nil {
+			return err
+		}
+		if stat != nil && stat.EphemeralOwner == le.lock.conn.SessionID() {
+			return nil
+		}
+	}
+	if err := ensurePath(le.lock, root); err != nil {
+		return err
+	}
+	data, _ := json.Marshal(candidate{Identity: identity, Priority: priority})
+	created, err := le.lock.Create(root+"/candidate-", data, FlagEphemeralSequence)
+	if err != nil {
+		return err
+	}
+	le.mu.Lock()
+	le.candidate = created
+	le.mu.Unlock()
+	return nil
+}
+
+// Release 节点属于 resourceId 时删除节点
+func (le *zkElector) Release(context.Context) error {
+	fullElectionID := le.getFullElectionID()
+	data, stat, err := le.lock.conn.Get(fullElectionID)
+	if errors.Is(err, zk.ErrNoNode) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if !strings.EqualFold(decodeLeader(data).Holder, le.resourceId) {
+		return nil
+	}
+	if err = le.lock.Delete(fullElectionID, stat.Version); errors.Is(err, zk.ErrNoNode) {
+		return nil
+	}
+	return err
+}
+
+// Close 关闭 zookeeper 连接, 临时节点随 session 一起删除
+func (le *zkElector) Close() error {
+	le.lock.Close()
+	return nil
+}
+
+func (le *zkElector) ensureRoot() error {
+	if exists, err := le.lock.Exists(le.config.ElectionRoot); err != nil {
+		return err
+	} else if !exists {
+		created, err := le.lock.Create(le.config.ElectionRoot, nil, FlagPermanent)
+		if errors.Is(err, zk.ErrNodeExists) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if !strings.EqualFold(created, le.config.ElectionRoot) {
+			return fmt.Errorf("failed to created root node, identity: %s want: %s created: %s", le.config.Identity, le.config.ElectionRoot, created)
+		}
+	}
+	return nil
+}
+
+func (le *zkElector) getFullElectionID() string {
+	return fmt.Sprintf("%s/%s", le.config.ElectionRoot, le.config.ElectionID)
+}
+
+// zkConn ResourceLock 使用的 zookeeper 连接, 由 *zk.Conn 实现
+type zkConn interface {
+	Create(path string, data []byte, flags int32, acl []zk.ACL) (string, error)
+	Delete(path string, version int32) error
+	Exists(path string) (bool, *zk.Stat, error)
+	ExistsW(path string) (bool, *zk.Stat, <-chan zk.Event, error)
+	Get(path string) ([]byte, *zk.Stat, error)
+	GetW(path string) ([]byte, *zk.Stat, <-chan zk.Event, error)
+	Set(path string, data []byte, version int32) (*zk.Stat, error)
+	Children(path string) ([]string, *zk.Stat, error)
+	ChildrenW(path string) ([]string, *zk.Stat, <-chan zk.Event, error)
+	SessionID() int64
+	State() zk.State
+	Close()
+}
+
+type ResourceLock struct {
+	logger log.Logger
+	conn   zkConn
+	clean  func()
+
+	mu        sync.Mutex
+	state     ConnState
+	listeners map[chan ConnState]struct{}
+	onState   func(ConnState)
+}
+
+type lockOptions struct {
+	sessionTimeout time.Duration
+	onState        func(ConnState)
+}
+
+type LockOption func(o *lockOptions)
+
+// WithSessionTimeout 设置 zookeeper session 超时时间, 默认 1s
+func WithSessionTimeout(timeout time.Duration) LockOption {
+	return func(o *lockOptions) {
+		if timeout > 0 {
+			o.sessionTimeout = timeout
+		}
+	}
+}
+
+// WithStateListener 连接状态变化时调用 f
+func WithStateListener(f func(ConnState)) LockOption {
+	return func(o *lockOptions) {
+		o.onState = f
+	}
+}
+
+func NewResourceLock(logger log.Logger, zkServers string, opts ...LockOption) (lock *ResourceLock, err error) {
+	servers := strings.Split(zkServers, ",")
+	if len(servers) == 0 {
+		err = errors.New("zk servers is required")
+		return
+	}
+	options := &lockOptions{sessionTimeout: DefaultSessionTimeout}
+	for _, o := range opts {
+		o(options)
+	}
+	conn, event, err := zk.Connect(servers, options.sessionTimeout)
+	if err != nil {
+		return nil, err
+	}
+	// 等待连接成功
+	for {
+		isConnected := false
+		select {
+		case connEvent := <-event:
+			if connEvent.State == zk.StateConnected {
+				isConnected = true
+				logger.Infof("connect to zookeeper server success!")
+			}
+		case <-time.After(time.Second * 3):
+			// 3秒仍未连接成功则返回连接超时
+			conn.Close()
+			return nil, errors.New("connect to zookeeper server timeout")
+		}
+		if isConnected {
+			break
+		}
+	}
+	return newResourceLock(logger, conn, event, options.onState), nil
+}
+
+// newResourceLock 使用已连接的 conn 创建 ResourceLock, 并根据 events 中的 session 事件维护连接状态
+func newResourceLock(logger log.Logger, conn zkConn, events <-chan zk.Event, onState func(ConnState)) *ResourceLock {
+	r := &ResourceLock{
+		logger:    logger,
+		conn:      conn,
+		listeners: make(map[chan ConnState]struct{}),
+		onState:   onState,
+		clean: func() {
+			conn.Close()
+		},
+	}
+	go r.dispatch(events)
+	return r
+}
+
+// dispatch 把 session 事件转换为连接状态, events 在连接关闭时关闭
+func (r *ResourceLock) dispatch(events <-chan zk.Event) {
+	for event := range events {
+		if event.Type != zk.EventSession {
+			continue
+		}
+		switch event.State {
+		case zk.StateHasSession:
+			r.setState(ConnConnected)
+		case zk.StateDisconnected, zk.StateConnecting:
+			if r.State() == ConnConnected {
+				r.setState(ConnSuspended)
+			}
+		case zk.StateExpired:
+			r.setState(ConnLost)
+		}
+	}
+}
+
+func (r *ResourceLock) setState(state ConnState) {
+	r.mu.Lock()
+	if r.state == state {
+		r.mu.Unlock()
+		return
+	}
+	old := r.state
+	r.state = state
+	for ch := range r.listeners {
+		// 只保留最新的状态
+		select {
+		case <-ch:
+		default:
+		}
+		ch <- state
+	}
+	onState := r.onState
+	r.mu.Unlock()
+	r.logger.Infof("zookeeper connection state changed from %s to %s", old, state)
+	if onState != nil {
+		onState(state)
+	}
+}
+
+// State 返回当前连接状态
+func (r *ResourceLock) State() ConnState {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.state
+}
+
+// subscribe 返回接收连接状态变化的 channel, 只保留最新的状态
+func (r *ResourceLock) subscribe() (<-chan ConnState, func()) {
+	ch := make(chan ConnState, 1)
+	r.mu.Lock()
+	r.listeners[ch] = struct{}{}
+	r.mu.Unlock()
+	return ch, func() {
+		r.mu.Lock()
+		delete(r.listeners, ch)
+		r.mu.Unlock()
+	}
+}
+
+func (r *ResourceLock) Close() {
+	if r.clean != nil {
+		r.clean()
+	}
+}
+
+const (
+	FlagPermanent         = 0                                  // 0: 永久保存
+	FlagEphemeral         = zk.FlagEphemeral                   // 1: 短暂,session断开则该节点也被删除
+	FlagEphemeralSequence = zk.FlagEphemeral | zk.FlagSequence // 3: 短暂且节点名称后追加递增序号
+)
+
+func (r *ResourceLock) Create(path string, data []byte, flags int32) (string, error) {
+	return r.conn.Create(path, data, flags, zk.WorldACL(zk.PermAll))
+}
+
+func (r *ResourceLock) Delete(path string, version int32) error {
+	return r.conn.Delete(path, version)
+}
+
+func (r *ResourceLock) Exists(path string) (exists bool, err error) {
+	exists, _, err = r.conn.Exists(path)
+	return
+}
+
+// ExistsW 返回 path 是否存在, 并监听 path 的创建、删除和数据变化
+func (r *ResourceLock) ExistsW(path string) (bool, *zk.Stat, <-chan zk.Event, error) {
+	return r.conn.ExistsW(path)
+}
+
+func (r *ResourceLock) Get(path string) (data []byte, err error) {
+	data, _, err = r.conn.Get(path)
+	return
+}
+
+// Watch 监听 path 的创建、删除和数据变化
+func (r *ResourceLock) Watch(path string) (ch <-chan zk.Event, err error) {
+	_, _, ch, err = r.conn.ExistsW(path)
+	return
+}
+
+func (r *ResourceLock) IsConnected() bool {
+	if r.conn == nil || r.conn.State() != zk.StateHasSession {
+		return false
+	}
+	return true
+}
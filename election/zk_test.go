@@ -0,0 +1,263 @@
+package election
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/neura-flow/common/log"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestZkElector(f *fakeZk, cfg Config) (*zkElector, *fakeConn) {
+	cfg.ElectionRoot = "/election"
+	cfg.ElectionID = "job"
+	lock, conn := f.lock()
+	return newZkElector(log.DefaultLogger(), &cfg, lock), conn
+}
+
+// holdAsync 在后台执行 Hold, 等待 leader 节点上的 watcher 注册后返回
+func holdAsync(t *testing.T, f *fakeZk, e Elector, ctx context.Context) <-chan error {
+	watching := f.watching("/election/job")
+	done := make(chan error, 1)
+	go func() { done <- e.Hold(ctx) }()
+	assert.Eventually(t, func() bool { return f.watching("/election/job") > watching }, time.Second, time.Millisecond)
+	return done
+}
+
+func TestZkElector_AcquireAndToken(t *testing.T) {
+	f := newFakeZk()
+	ctx := context.Background()
+	a, _ := newTestZkElector(f, Config{})
+	b, _ := newTestZkElector(f, Config{})
+
+	ok, err := a.Acquire(ctx, LeaderInfo{})
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	ok, _ = a.Acquire(ctx, LeaderInfo{})
+	assert.True(t, ok)
+	ok, err = b.Acquire(ctx, LeaderInfo{})
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	first := a.Token()
+	assert.Greater(t, first, int64(0))
+
+	assert.NoError(t, a.Release(ctx))
+	ok, _ = b.Acquire(ctx, LeaderInfo{})
+	assert.True(t, ok)
+	assert.Greater(t, b.Token(), first)
+}
+
+func TestZkElector_HoldNodeDeleted(t *testing.T) {
+	f := newFakeZk()
+	ctx := context.Background()
+	a, _ := newTestZkElector(f, Config{})
+	_, admin := f.lock()
+	ok, _ := a.Acquire(ctx, LeaderInfo{})
+	assert.True(t, ok)
+
+	done := holdAsync(t, f, a, ctx)
+	// 数据变化不影响 leader
+	_, err := admin.Set("/election/job", []byte("x"), -1)
+	assert.NoError(t, err)
+	select {
+	case err := <-done:
+		t.Fatalf("hold returned after data change: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	assert.NoError(t, admin.Delete("/election/job", -1))
+	select {
+	case err := <-done:
+		assert.ErrorIs(t, err, ErrLeaseLost)
+	case <-time.After(time.Second):
+		t.Fatal("hold not returned after node deleted")
+	}
+}
+
+func TestZkElector_Suspended(t *testing.T) {
+	f := newFakeZk()
+	ctx := context.Background()
+	states := make(chan ConnState, 4)
+	a, conn := newTestZkElector(f, Config{SuspendGrace: 100 * time.Millisecond})
+	a.lock.mu.Lock()
+	a.lock.onState = func(s ConnState) { states <- s }
+	a.lock.mu.Unlock()
+	ok, _ := a.Acquire(ctx, LeaderInfo{})
+	assert.True(t, ok)
+
+	// grace 内恢复连接, 继续持有
+	done := holdAsync(t, f, a, ctx)
+	conn.disconnect()
+	time.Sleep(30 * time.Millisecond)
+	conn.reconnect()
+	select {
+	case err := <-done:
+		t.Fatalf("hold returned after reconnect: %v", err)
+	case <-time.After(150 * time.Millisecond):
+	}
+
+	// 超过 grace 未恢复, 放弃 leader
+	conn.disconnect()
+	select {
+	case err := <-done:
+		assert.ErrorIs(t, err, ErrLeaseLost)
+	case <-time.After(time.Second):
+		t.Fatal("hold not returned after suspend grace")
+	}
+	assert.Equal(t, ConnSuspended, a.lock.State())
+	for _, want := range []ConnState{ConnSuspended, ConnConnected, ConnSuspended} {
+		assert.Equal(t, want, <-states)
+	}
+}
+
+func TestZkElector_SessionExpired(t *testing.T) {
+	f := newFakeZk()
+	ctx := context.Background()
+	a, conn := newTestZkElector(f, Config{SuspendGrace: time.Minute})
+	b, _ := newTestZkElector(f, Config{})
+	ok, _ := a.Acquire(ctx, LeaderInfo{})
+	assert.True(t, ok)
+
+	done := holdAsync(t, f, a, ctx)
+	conn.disconnect()
+	conn.expire()
+	select {
+	case err := <-done:
+		assert.ErrorIs(t, err, ErrLeaseLost)
+	case <-time.After(time.Second):
+		t.Fatal("hold not returned after session expired")
+	}
+	assert.Eventually(t, func() bool { return a.lock.State() == ConnLost }, time.Second, time.Millisecond)
+	ok, _ = b.Acquire(ctx, LeaderInfo{})
+	assert.True(t, ok)
+}
+
+func TestElection_ZkFencingToken(t *testing.T) {
+	f := newFakeZk()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	tokens := make(chan int64, 4)
+	stopped := make(chan struct{}, 4)
+	newElection := func(identity string) (*Election, *fakeConn) {
+		cfg := Config{Identity: identity, RetryPeriod: 10 * time.Millisecond, SuspendGrace: 20 * time.Millisecond}
+		elector, conn := newTestZkElector(f, cfg)
+		cfg.Elector = elector
+		cfg.Callbacks = Callbacks{
+			OnStartedLeading: func(ctx context.Context) {
+				token, _ := FencingToken(ctx)
+				tokens <- token
+				<-ctx.Done()
+				stopped <- struct{}{}
+			},
+		}
+		le, err := NewElection(ctx, log.DefaultLogger(), &cfg)
+		assert.NoError(t, err)
+		return le, conn
+	}
+	a, conn := newElection("a")
+	b, _ := newElection("b")
+	go a.Run()
+	first := <-tokens
+	assert.True(t, a.IsLeader())
+	assert.Equal(t, first, a.FencingToken())
+	go b.Run()
+
+	// a 断开超过 grace 后 OnStartedLeading 的 ctx 被取消, session 过期后 b 成为 leader
+	conn.disconnect()
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("leader ctx not canceled after suspend grace")
+	}
+	assert.Eventually(t, func() bool { return !a.IsLeader() }, time.Second, 10*time.Millisecond)
+	conn.expire()
+	select {
+	case token := <-tokens:
+		assert.Greater(t, token, first)
+		assert.True(t, b.IsLeader())
+		assert.Equal(t, token, b.FencingToken())
+	case <-time.After(time.Second):
+		t.Fatal("no new leader after session expired")
+	}
+}
+
+func TestElection_ZkLeader(t *testing.T) {
+	f := newFakeZk()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	observed := make(chan string, 8)
+	newElection := func(identity string) *Election {
+		cfg := Config{Identity: identity, Address: identity + ":8080", RetryPeriod: 10 * time.Millisecond}
+		elector, _ := newTestZkElector(f, cfg)
+		cfg.Elector = elector
+		cfg.Callbacks.OnNewLeader = func(leader string) { observed <- identity + "->" + leader }
+		le, err := NewElection(ctx, log.DefaultLogger(), &cfg)
+		assert.NoError(t, err)
+		return le
+	}
+	a := newElection("a")
+	b := newElection("b")
+	go a.Run()
+	assert.Equal(t, "a->a", <-observed)
+	go b.Run()
+	assert.Equal(t, "b->a", <-observed)
+	leader, ok := b.Leader()
+	assert.True(t, ok)
+	assert.Equal(t, "a:8080", leader.Address)
+
+	// leader 节点被删除后 b 通过 watch 观察到新的 leader
+	_, admin := f.lock()
+	assert.NoError(t, admin.Delete("/election/job", -1))
+	got := map[string]bool{}
+	for len(got) < 2 {
+		select {
+		case o := <-observed:
+			got[o] = true
+		case <-time.After(time.Second):
+			t.Fatalf("new leader not observed, got: %v", got)
+		}
+	}
+	leader, _ = a.Leader()
+	assert.True(t, got["a->"+leader.Identity])
+	assert.True(t, got["b->"+leader.Identity])
+}
+
+func TestElection_ZkResign(t *testing.T) {
+	f := newFakeZk()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	started := make(chan string, 4)
+	newElection := func(identity string, priority int) *Election {
+		cfg := Config{Identity: identity, RetryPeriod: 10 * time.Millisecond, Priority: priority}
+		elector, _ := newTestZkElector(f, cfg)
+		cfg.Elector = elector
+		cfg.Callbacks.OnStartedLeading = func(context.Context) { started <- identity }
+		le, err := NewElection(ctx, log.DefaultLogger(), &cfg)
+		assert.NoError(t, err)
+		return le
+	}
+	a := newElection("a", 0)
+	b := newElection("b", 0)
+	go a.Run()
+	assert.Equal(t, "a", <-started)
+	go b.Run()
+
+	// Resign 删除 leader 节点, b 接管
+	assert.NoError(t, a.Resign(ctx))
+	assert.Equal(t, "b", <-started)
+	leader, ok, err := a.elector.Leader(ctx)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "b", leader.Identity)
+
+	// 优先级更高的 c 加入后 b 主动放弃 leader
+	c := newElection("c", 1)
+	go c.Run()
+	select {
+	case id := <-started:
+		assert.Equal(t, "c", id)
+	case <-time.After(time.Second):
+		t.Fatal("leader not handed over to preferred candidate")
+	}
+}
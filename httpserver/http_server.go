@@ -57,6 +57,18 @@ func (s *HttpServer) HandlePrefix(prefix string, fn http.HandlerFunc) {
 	})
 }
 
+// HandleLogLevel 在 path 上挂载查看(GET)和修改(PUT)日志级别的接口, logger 需实现 log.LevelControl
+func (s *HttpServer) HandleLogLevel(path string) error {
+	lc, ok := s.logger.(log.LevelControl)
+	if !ok {
+		return fmt.Errorf("logger %T does not support level control", s.logger)
+	}
+	h := gin.WrapF(lc.Levels().Handler())
+	s.engine.GET(path, h)
+	s.engine.PUT(path, h)
+	return nil
+}
+
 func (s *HttpServer) Start() error {
 	if !atomic.CompareAndSwapInt32(&s.running, 0, 1) {
 		return nil
@@ -1,11 +1,15 @@
 package httpserver_test
 
 import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/gin-gonic/gin"
 	"github.com/neura-flow/common/httpserver"
 	"github.com/neura-flow/common/log"
+	"github.com/stretchr/testify/assert"
 )
 
 func TestHttpServer(t *testing.T) {
@@ -18,3 +22,18 @@ func TestHttpServer(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestHttpServer_HandleLogLevel(t *testing.T) {
+	logger := log.DefaultLogger()
+	logger.Named("election")
+	svr := httpserver.NewHttpServer(logger, &httpserver.Config{GinMode: gin.TestMode})
+	assert.NoError(t, svr.HandleLogLevel("/loggers"))
+
+	w := httptest.NewRecorder()
+	svr.Engine().ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/loggers", strings.NewReader(`{"name":"election","level":"debug"}`)))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	svr.Engine().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/loggers", nil))
+	assert.JSONEq(t, `{"":"info","election":"debug"}`, w.Body.String())
+}
@@ -0,0 +1,195 @@
+package log
+
+import (
+	"bufio"
+	"sync"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// 异步写入队列满时的处理策略
+const (
+	PolicyBlock      = "block"      // 阻塞直到队列有空位
+	PolicyDropOldest = "dropOldest" // 丢弃队列中最早的日志
+	PolicyDropNewest = "dropNewest" // 丢弃当前写入的日志
+
+	defaultAsyncSize          = 8192
+	defaultAsyncFlushInterval = time.Second
+	asyncBufferSize           = 256 * 1024
+)
+
+// AsyncStats 异步写入队列的状态
+type AsyncStats struct {
+	Depth    int    `json:"depth"`
+	Capacity int    `json:"capacity"`
+	Dropped  uint64 `json:"dropped"`
+}
+
+// asyncWriter 把日志写入有界环形队列, 由后台 goroutine 批量写入 out 并定期 flush.
+// Close 之后的写入直接同步写入 out
+type asyncWriter struct {
+	out    zapcore.WriteSyncer
+	buf    *bufio.Writer // 只由后台 goroutine 使用
+	policy string
+
+	mu       sync.Mutex
+	notEmpty *sync.Cond
+	notFull  *sync.Cond
+	items    [][]byte
+	head     int
+	count    int
+	dropped  uint64
+	flushDue bool
+	syncReqs []chan error
+	closed   bool
+	done     chan struct{}
+
+	directMu sync.Mutex // Close 之后同步写入时使用
+}
+
+func newAsyncWriter(out zapcore.WriteSyncer, cfg AsyncConfig) *asyncWriter {
+	w := &asyncWriter{
+		out:    out,
+		buf:    bufio.NewWriterSize(out, asyncBufferSize),
+		policy: cfg.Policy,
+		items:  make([][]byte, positive(cfg.Size, defaultAsyncSize)),
+		done:   make(chan struct{}),
+	}
+	w.notEmpty = sync.NewCond(&w.mu)
+	w.notFull = sync.NewCond(&w.mu)
+	go w.run()
+	go w.tick(duration(cfg.FlushInterval, defaultAsyncFlushInterval))
+	return w
+}
+
+func (w *asyncWriter) Write(p []byte) (int, error) {
+	// zap 会复用 p, 需要复制
+	b := make([]byte, len(p))
+	copy(b, p)
+	w.mu.Lock()
+	for !w.closed && w.count == len(w.items) {
+		switch w.policy {
+		case PolicyDropNewest:
+			w.dropped++
+			w.mu.Unlock()
+			return len(p), nil
+		case PolicyDropOldest:
+			w.pop()
+			w.dropped++
+		default:
+			w.notFull.Wait()
+		}
+	}
+	if w.closed {
+		w.mu.Unlock()
+		<-w.done
+		w.directMu.Lock()
+		defer w.directMu.Unlock()
+		return w.out.Write(p)
+	}
+	w.items[(w.head+w.count)%len(w.items)] = b
+	w.count++
+	w.notEmpty.Signal()
+	w.mu.Unlock()
+	return len(p), nil
+}
+
+// pop 取出队首的日志, 调用方需持有锁
+func (w *asyncWriter) pop() []byte {
+	b := w.items[w.head]
+	w.items[w.head] = nil
+	w.head = (w.head + 1) % len(w.items)
+	w.count--
+	return b
+}
+
+// Sync 等待调用前写入的日志全部写入 out 并 flush
+func (w *asyncWriter) Sync() error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return w.out.Sync()
+	}
+	ch := make(chan error, 1)
+	w.syncReqs = append(w.syncReqs, ch)
+	w.notEmpty.Signal()
+	w.mu.Unlock()
+	return <-ch
+}
+
+// Close 写入队列中剩余的日志并停止后台 goroutine
+func (w *asyncWriter) Close() error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return nil
+	}
+	w.closed = true
+	w.notEmpty.Broadcast()
+	w.notFull.Broadcast()
+	w.mu.Unlock()
+	<-w.done
+	return nil
+}
+
+func (w *asyncWriter) Stats() AsyncStats {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return AsyncStats{Depth: w.count, Capacity: len(w.items), Dropped: w.dropped}
+}
+
+func (w *asyncWriter) run() {
+	defer close(w.done)
+	batch := make([][]byte, 0, 256)
+	for {
+		w.mu.Lock()
+		for w.count == 0 && len(w.syncReqs) == 0 && !w.flushDue && !w.closed {
+			w.notEmpty.Wait()
+		}
+		batch = batch[:0]
+		for w.count > 0 {
+			batch = append(batch, w.pop())
+		}
+		reqs, closed := w.syncReqs, w.closed
+		flush := w.flushDue || len(reqs) > 0 || closed
+		w.syncReqs, w.flushDue = nil, false
+		w.notFull.Broadcast()
+		w.mu.Unlock()
+
+		for _, b := range batch {
+			_, _ = w.buf.Write(b)
+		}
+		var err error
+		if flush {
+			err = w.buf.Flush()
+			if len(reqs) > 0 || closed {
+				if e := w.out.Sync(); err == nil {
+					err = e
+				}
+			}
+		}
+		for _, ch := range reqs {
+			ch <- err
+		}
+		if closed {
+			return
+		}
+	}
+}
+
+func (w *asyncWriter) tick(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-ticker.C:
+			w.mu.Lock()
+			w.flushDue = true
+			w.notEmpty.Signal()
+			w.mu.Unlock()
+		}
+	}
+}
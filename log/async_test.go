@@ -0,0 +1,115 @@
+package log
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+// gateWriter 在 gate 关闭前阻塞 Sync, 用于模拟写入缓慢
+type gateWriter struct {
+	mu      sync.Mutex
+	buf     bytes.Buffer
+	gate    chan struct{}
+	once    sync.Once
+	entered chan struct{}
+}
+
+func (w *gateWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.Write(p)
+}
+
+func (w *gateWriter) Sync() error {
+	if w.entered != nil {
+		w.once.Do(func() { close(w.entered) })
+	}
+	<-w.gate
+	return nil
+}
+
+func (w *gateWriter) String() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.String()
+}
+
+// fill 阻塞后台写入后向队列写入 n 条日志
+func fill(t *testing.T, policy string, n int) (*asyncWriter, *gateWriter) {
+	out := &gateWriter{gate: make(chan struct{}), entered: make(chan struct{})}
+	w := newAsyncWriter(out, AsyncConfig{Size: 3, Policy: policy, FlushInterval: "1h"})
+	go func() {
+		_ = w.Sync()
+	}()
+	// 等待后台 goroutine 阻塞在 out.Sync
+	<-out.entered
+	for i := 0; i < n; i++ {
+		_, err := w.Write([]byte{byte('a' + i), '\n'})
+		assert.NoError(t, err)
+	}
+	return w, out
+}
+
+func TestAsyncWriter_Drop(t *testing.T) {
+	w, out := fill(t, PolicyDropNewest, 5)
+	assert.Equal(t, AsyncStats{Depth: 3, Capacity: 3, Dropped: 2}, w.Stats())
+	close(out.gate)
+	assert.NoError(t, w.Close())
+	assert.Equal(t, "a\nb\nc\n", out.String())
+
+	w, out = fill(t, PolicyDropOldest, 5)
+	assert.Equal(t, uint64(2), w.Stats().Dropped)
+	close(out.gate)
+	assert.NoError(t, w.Sync())
+	assert.Equal(t, "c\nd\ne\n", out.String())
+	assert.NoError(t, w.Close())
+
+	// 关闭后同步写入
+	_, _ = w.Write([]byte("f\n"))
+	assert.Equal(t, "c\nd\ne\nf\n", out.String())
+}
+
+func TestAsyncWriter_Block(t *testing.T) {
+	out := &gateWriter{gate: make(chan struct{})}
+	close(out.gate)
+	w := newAsyncWriter(out, AsyncConfig{Size: 2})
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				_, _ = w.Write([]byte("x\n"))
+			}
+		}()
+	}
+	wg.Wait()
+	assert.NoError(t, w.Sync())
+	assert.Equal(t, 400, strings.Count(out.String(), "x"))
+	assert.Equal(t, uint64(0), w.Stats().Dropped)
+	assert.NoError(t, w.Close())
+}
+
+func TestZapLogger_AsyncClose(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	logger, err := NewLogger(&Config{
+		File:  FileConfig{Enabled: true, Path: path},
+		Async: AsyncConfig{Enabled: true, FlushInterval: "1h"},
+	})
+	assert.NoError(t, err)
+	for i := 0; i < 10; i++ {
+		logger.Named("worker").Infof("line %d", i)
+	}
+	assert.NoError(t, logger.Close())
+	buf, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, 10, strings.Count(string(buf), `"logger":"worker"`))
+	assert.Equal(t, 6, testutil.CollectAndCount(NewCollector(logger)))
+}
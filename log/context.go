@@ -0,0 +1,73 @@
+package log
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/neura-flow/common/metadata"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// ContextExtractor 从 context 中提取需要附加到日志的字段
+type ContextExtractor func(ctx context.Context) []metadata.KV
+
+var (
+	contextExtractorsMu sync.RWMutex
+	contextExtractors   []ContextExtractor
+)
+
+// RegisterContextExtractor 注册自定义的 context 字段提取函数
+func RegisterContextExtractor(e ContextExtractor) {
+	contextExtractorsMu.Lock()
+	defer contextExtractorsMu.Unlock()
+	contextExtractors = append(contextExtractors, e)
+}
+
+// ContextFields 返回 *Ctx 方法附加到日志的字段: context 中的 metadata.Metadata,
+// OpenTelemetry 的 traceId/spanId (存在时覆盖 metadata 中的 traceId), 以及自定义提取的字段
+func ContextFields(ctx context.Context) []metadata.KV {
+	if ctx == nil {
+		return nil
+	}
+	var list []metadata.KV
+	if md, ok := ctx.Value(metadata.Key{}).(metadata.Metadata); ok && md != nil {
+		list = md.List()
+		sort.Slice(list, func(i, j int) bool {
+			return list[i].Key() < list[j].Key()
+		})
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		list = setKV(list, metadata.NewKV(metadata.KeyTraceId, sc.TraceID().String()))
+		list = setKV(list, metadata.NewKV(metadata.KeySpanId, sc.SpanID().String()))
+	}
+	contextExtractorsMu.RLock()
+	extractors := contextExtractors
+	contextExtractorsMu.RUnlock()
+	for _, e := range extractors {
+		for _, kv := range e(ctx) {
+			list = setKV(list, kv)
+		}
+	}
+	return list
+}
+
+func setKV(list []metadata.KV, kv metadata.KV) []metadata.KV {
+	for i, item := range list {
+		if item.Key() == kv.Key() {
+			list[i] = kv
+			return list
+		}
+	}
+	return append(list, kv)
+}
+
+func contextZapFields(ctx context.Context) []zap.Field {
+	kvs := ContextFields(ctx)
+	fields := make([]zap.Field, 0, len(kvs))
+	for _, kv := range kvs {
+		fields = append(fields, zap.Any(kv.Key(), kv.Value()))
+	}
+	return fields
+}
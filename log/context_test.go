@@ -0,0 +1,89 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/neura-flow/common/metadata"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap/zapcore"
+)
+
+// newBufferLogger 返回输出到 buf 的 ZapLogger
+func newBufferLogger(t *testing.T, cfg *Config) (*ZapLogger, *bytes.Buffer) {
+	logger, err := NewZapLogger(cfg)
+	assert.NoError(t, err)
+	l := logger.(*ZapLogger)
+	buf := &bytes.Buffer{}
+	l.writeSyncer = zapcore.AddSync(buf)
+	l.zapCore = l.newCore(l.level)
+	l.initLogger()
+	return l, buf
+}
+
+func decodeLine(t *testing.T, buf *bytes.Buffer) map[string]interface{} {
+	line, err := buf.ReadBytes('\n')
+	assert.NoError(t, err)
+	m := make(map[string]interface{})
+	assert.NoError(t, jsoniter.Unmarshal(line, &m))
+	return m
+}
+
+func TestZapLogger_Ctx(t *testing.T) {
+	l, buf := newBufferLogger(t, &Config{Caller: CallerConfig{Enabled: true}})
+	ctx := metadata.ToContext(context.Background(), metadata.FromMap(map[string]interface{}{
+		metadata.KeyTraceId: "md-trace",
+		metadata.KeyBiz:     "order",
+	}))
+
+	l.InfoCtx(ctx, "hello %s", "world")
+	m := decodeLine(t, buf)
+	assert.Equal(t, "hello world", m["message"])
+	assert.Equal(t, "md-trace", m[metadata.KeyTraceId])
+	assert.Equal(t, "order", m[metadata.KeyBiz])
+	assert.Contains(t, m["caller"], "context_test.go")
+
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	ctx = trace.ContextWithSpanContext(ctx, trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: traceID,
+		SpanID:  spanID,
+	}))
+	assert.NoError(t, l.LogCtx(ctx, LevelWarn, "msg", "done", "cost", 3))
+	m = decodeLine(t, buf)
+	assert.Equal(t, "done", m["message"])
+	assert.Equal(t, "warn", m["level"])
+	assert.Equal(t, float64(3), m["cost"])
+	assert.Equal(t, traceID.String(), m[metadata.KeyTraceId])
+	assert.Equal(t, spanID.String(), m[metadata.KeySpanId])
+
+	l.DebugCtx(ctx, "ignored")
+	assert.Equal(t, 0, buf.Len())
+}
+
+type extractorKey struct{}
+
+func TestRegisterContextExtractor(t *testing.T) {
+	ctx := context.WithValue(context.Background(), extractorKey{}, "v")
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			_ = ContextFields(ctx)
+		}
+	}()
+	// 与 ContextFields 并发注册
+	RegisterContextExtractor(func(ctx context.Context) []metadata.KV {
+		if v, ok := ctx.Value(extractorKey{}).(string); ok {
+			return []metadata.KV{metadata.NewKV("tenant", v)}
+		}
+		return nil
+	})
+	<-done
+	kvs := ContextFields(ctx)
+	assert.Len(t, kvs, 1)
+	assert.Equal(t, "tenant", kvs[0].Key())
+}
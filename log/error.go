@@ -0,0 +1,170 @@
+package log
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/neura-flow/common/exception"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// maxErrorCauses 最多输出的 cause 数量, 避免 Unwrap 成环时无限循环
+const maxErrorCauses = 16
+
+// ErrorField 把 err 输出为结构化字段, 包括:
+//   - message、type
+//   - 错误链中 exception.BizException 的 status、code、msg、traceId、errorCode、parameters
+//   - 错误链中 exception.PanicException 的 panic 以及按帧拆分的 stack
+//   - errors.Unwrap 得到的 causes, 每个 cause 包含 message 和 type
+//
+// Log、LogCtx 的 kvs 中类型为 error 的值都会通过 ErrorField 输出
+func ErrorField(key string, err error) zap.Field {
+	if err == nil {
+		return zap.Skip()
+	}
+	return zap.Object(key, &errorObject{err: err})
+}
+
+type errorObject struct {
+	err    error
+	redact func(string) string
+}
+
+func (e *errorObject) message(err error) string {
+	var msg string
+	switch v := err.(type) {
+	case *exception.BizException:
+		msg = v.Msg
+		if msg == "" {
+			msg = v.Code
+		}
+	case exception.PanicException:
+		msg = panicMessage(v)
+	default:
+		msg = err.Error()
+	}
+	if e.redact != nil {
+		msg = e.redact(msg)
+	}
+	return msg
+}
+
+func (e *errorObject) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("message", e.message(e.err))
+	enc.AddString("type", fmt.Sprintf("%T", e.err))
+
+	var biz *exception.BizException
+	if errors.As(e.err, &biz) {
+		enc.AddInt("status", biz.Status)
+		enc.AddString("code", biz.Code)
+		if biz.Msg != "" {
+			enc.AddString("msg", e.message(biz))
+		}
+		if biz.TraceId != "" {
+			enc.AddString("traceId", biz.TraceId)
+		}
+		if biz.ErrCode != "" {
+			enc.AddString("errorCode", biz.ErrCode)
+		}
+		if len(biz.Parameters) > 0 {
+			_ = enc.AddArray("parameters", zapcore.ArrayMarshalerFunc(func(arr zapcore.ArrayEncoder) error {
+				for _, p := range biz.Parameters {
+					if p == nil {
+						continue
+					}
+					_ = arr.AppendObject(zapcore.ObjectMarshalerFunc(func(obj zapcore.ObjectEncoder) error {
+						obj.AddString("name", p.Name)
+						obj.AddString("value", p.Value)
+						return nil
+					}))
+				}
+				return nil
+			}))
+		}
+	}
+
+	var pe exception.PanicException
+	if errors.As(e.err, &pe) {
+		enc.AddString("panic", fmt.Sprint(pe.Unwrap()))
+		frames := parseStack(pe.Stack())
+		_ = enc.AddArray("stack", zapcore.ArrayMarshalerFunc(func(arr zapcore.ArrayEncoder) error {
+			for _, f := range frames {
+				_ = arr.AppendObject(f)
+			}
+			return nil
+		}))
+	}
+
+	var causes []error
+	for cause := errors.Unwrap(e.err); cause != nil && len(causes) < maxErrorCauses; cause = errors.Unwrap(cause) {
+		causes = append(causes, cause)
+	}
+	if len(causes) > 0 {
+		_ = enc.AddArray("causes", zapcore.ArrayMarshalerFunc(func(arr zapcore.ArrayEncoder) error {
+			for _, cause := range causes {
+				cause := cause
+				_ = arr.AppendObject(zapcore.ObjectMarshalerFunc(func(obj zapcore.ObjectEncoder) error {
+					obj.AddString("message", e.message(cause))
+					obj.AddString("type", fmt.Sprintf("%T", cause))
+					return nil
+				}))
+			}
+			return nil
+		}))
+	}
+	return nil
+}
+
+// panicMessage 返回不含调用栈的 panic 信息
+func panicMessage(pe exception.PanicException) string {
+	msg := pe.Error()
+	if i := strings.Index(msg, "\n\n"); i >= 0 {
+		msg = msg[:i]
+	}
+	return msg
+}
+
+// stackFrame 调用栈中的一帧
+type stackFrame struct {
+	Function string
+	File     string
+}
+
+func (f stackFrame) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("function", f.Function)
+	enc.AddString("file", f.File)
+	return nil
+}
+
+// parseStack 解析 runtime.Stack 格式的调用栈, 只解析第一个 goroutine
+func parseStack(stack string) []stackFrame {
+	var frames []stackFrame
+	lines := strings.Split(strings.TrimSpace(stack), "\n")
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		if line == "" {
+			if len(frames) > 0 {
+				break
+			}
+			continue
+		}
+		if strings.HasPrefix(line, "goroutine ") {
+			continue
+		}
+		f := stackFrame{Function: line}
+		if p := strings.LastIndex(line, "("); p > 0 {
+			f.Function = line[:p]
+		}
+		if i+1 < len(lines) && strings.HasPrefix(lines[i+1], "\t") {
+			i++
+			f.File = strings.TrimSpace(lines[i])
+			if p := strings.LastIndex(f.File, " +0x"); p > 0 {
+				f.File = f.File[:p]
+			}
+		}
+		frames = append(frames, f)
+	}
+	return frames
+}
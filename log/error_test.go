@@ -0,0 +1,63 @@
+package log
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/neura-flow/common/exception"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestErrorField_BizException(t *testing.T) {
+	l, buf := newBufferLogger(t, &Config{})
+	biz := exception.NewBizException(400, "INVALID_ARG",
+		exception.WithMsg("bad name"),
+		exception.WithErrCode("E1001"),
+		exception.WithParams(&exception.Parameter{Name: "name", Value: "x"}))
+	err := fmt.Errorf("create user: %w", biz)
+	assert.NoError(t, l.Log(LevelError, "msg", "failed", "error", err))
+
+	m := decodeLine(t, buf)["error"].(map[string]interface{})
+	assert.Equal(t, "create user: "+biz.Error(), m["message"])
+	assert.Equal(t, "*fmt.wrapError", m["type"])
+	assert.Equal(t, float64(400), m["status"])
+	assert.Equal(t, "INVALID_ARG", m["code"])
+	assert.Equal(t, "bad name", m["msg"])
+	assert.Equal(t, "E1001", m["errorCode"])
+	assert.Equal(t, []interface{}{map[string]interface{}{"name": "name", "value": "x"}}, m["parameters"])
+	assert.Equal(t, []interface{}{map[string]interface{}{"message": "bad name", "type": "*exception.BizException"}}, m["causes"])
+}
+
+func TestErrorField_PanicException(t *testing.T) {
+	l, buf := newBufferLogger(t, &Config{})
+	var pe exception.PanicException
+	func() {
+		defer exception.Recover(func(e exception.PanicException) bool {
+			pe = e
+			return true
+		})
+		panic("boom")
+	}()
+	assert.NoError(t, l.Log(LevelError, "msg", "recovered", "error", pe))
+
+	m := decodeLine(t, buf)["error"].(map[string]interface{})
+	assert.Equal(t, "panic: boom", m["message"])
+	assert.Equal(t, "boom", m["panic"])
+	stack := m["stack"].([]interface{})
+	assert.NotEmpty(t, stack)
+	frame := stack[0].(map[string]interface{})
+	assert.Contains(t, frame["function"], "TestErrorField_PanicException")
+	assert.Contains(t, frame["file"], "error_test.go:")
+}
+
+func TestParseStack(t *testing.T) {
+	stack := "goroutine 1 [running]:\n" +
+		"main.f(0x1)\n\t/app/main.go:10 +0x1d\n" +
+		"main.main()\n\t/app/main.go:5 +0x10\n\n" +
+		"goroutine 2 [select]:\n" +
+		"main.g()\n\t/app/main.go:20 +0x1d\n"
+	assert.Equal(t, []stackFrame{
+		{Function: "main.f", File: "/app/main.go:10"},
+		{Function: "main.main", File: "/app/main.go:5"},
+	}, parseStack(stack))
+}
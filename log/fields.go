@@ -0,0 +1,160 @@
+package log
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/neura-flow/common/host"
+)
+
+// FieldResolver 返回动态字段的值, 在创建 logger 时调用
+type FieldResolver func() (string, error)
+
+var (
+	fieldResolversMu sync.RWMutex
+	// fieldResolvers Config.Fields 中 ${name} 可以引用的动态值
+	fieldResolvers = map[string]FieldResolver{
+		"hostname": os.Hostname,
+		"ip":       host.IP,
+		"pid": func() (string, error) {
+			return strconv.Itoa(os.Getpid()), nil
+		},
+		"process": func() (string, error) {
+			return filepath.Base(os.Args[0]), nil
+		},
+		"go.version": func() (string, error) {
+			return runtime.Version(), nil
+		},
+		"build.module":   buildInfo(func(bi *debug.BuildInfo) string { return bi.Main.Path }),
+		"build.version":  buildInfo(func(bi *debug.BuildInfo) string { return bi.Main.Version }),
+		"build.revision": buildSetting("vcs.revision"),
+		"build.time":     buildSetting("vcs.time"),
+	}
+)
+
+// RegisterFieldResolver 注册 Config.Fields 中可以通过 ${name} 引用的动态值, 同名的会被替换
+func RegisterFieldResolver(name string, r FieldResolver) {
+	fieldResolversMu.Lock()
+	defer fieldResolversMu.Unlock()
+	fieldResolvers[name] = r
+}
+
+func buildInfo(get func(bi *debug.BuildInfo) string) FieldResolver {
+	return func() (string, error) {
+		bi, ok := debug.ReadBuildInfo()
+		if !ok {
+			return "", fmt.Errorf("build info not available")
+		}
+		return get(bi), nil
+	}
+}
+
+func buildSetting(key string) FieldResolver {
+	return buildInfo(func(bi *debug.BuildInfo) string {
+		for _, s := range bi.Settings {
+			if s.Key == key {
+				return s.Value
+			}
+		}
+		return ""
+	})
+}
+
+// parseFields 解析 key1=val1,key2=val2 格式的字段, 值中可以使用以下占位符:
+//
+//	${hostname}                            # 主机名
+//	${ip}                                  # 本机 IP, 见 host.IP
+//	${pid}                                 # 进程 ID
+//	${process}                             # 程序名
+//	${env:APP_ENV:-dev}                    # 环境变量, 不存在时使用默认值
+//	${go.version}                          # Go 版本
+//	${build.module} ${build.version}       # 主模块路径及版本
+//	${build.revision} ${build.time}        # vcs 提交及时间
+//	$${literal}                            # 转义, 输出 ${literal}
+//
+// 通过 config 加载时 config 会先解析占位符, 除 ${env:X} 外需要写成 $${hostname} 以留给 logger 解析
+func parseFields(s string) ([][2]string, error) {
+	var fields [][2]string
+	for _, f := range strings.Split(s, ",") {
+		f = strings.TrimSpace(f)
+		if f == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(f, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid log field %q, format: key1=val1,key2=val2", f)
+		}
+		value, err := expandField(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("log field %q: %w", key, err)
+		}
+		fields = append(fields, [2]string{key, value})
+	}
+	return fields, nil
+}
+
+// expandField 替换 s 中的所有占位符
+func expandField(s string) (string, error) {
+	var buf strings.Builder
+	for {
+		start := strings.Index(s, "${")
+		if start < 0 {
+			buf.WriteString(s)
+			return buf.String(), nil
+		}
+		if start > 0 && s[start-1] == '$' {
+			buf.WriteString(s[:start-1] + "${")
+			s = s[start+2:]
+			continue
+		}
+		end := strings.IndexByte(s[start:], '}')
+		if end < 0 {
+			return "", fmt.Errorf("unclosed placeholder in %q", s)
+		}
+		end += start
+		v, err := resolveField(s[start+2 : end])
+		if err != nil {
+			return "", err
+		}
+		buf.WriteString(s[:start])
+		buf.WriteString(v)
+		s = s[end+1:]
+	}
+}
+
+func resolveField(expr string) (string, error) {
+	ref, def, hasDef := strings.Cut(expr, ":-")
+	if name, ok := strings.CutPrefix(ref, "env:"); ok {
+		if v, ok := os.LookupEnv(name); ok {
+			return v, nil
+		}
+		if hasDef {
+			return def, nil
+		}
+		return "", fmt.Errorf("environment variable %s is not set", name)
+	}
+	fieldResolversMu.RLock()
+	r, ok := fieldResolvers[ref]
+	fieldResolversMu.RUnlock()
+	if !ok {
+		return "", fmt.Errorf("unknown placeholder ${%s}", expr)
+	}
+	v, err := r()
+	if err != nil || v == "" {
+		if hasDef {
+			return def, nil
+		}
+		if err == nil {
+			err = fmt.Errorf("${%s} is empty", expr)
+		}
+		return "", err
+	}
+	return v, nil
+}
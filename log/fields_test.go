@@ -0,0 +1,47 @@
+package log
+
+import (
+	"os"
+	"runtime"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseFields(t *testing.T) {
+	t.Setenv("LOG_TEST_ENV", "prod")
+	hostname, _ := os.Hostname()
+	fields, err := parseFields("app=order, env=${env:LOG_TEST_ENV},region=${env:LOG_TEST_REGION:-cn}, host=${hostname}-${pid},go=${go.version},raw=$${ip},q=a=b")
+	assert.NoError(t, err)
+	assert.Equal(t, [][2]string{
+		{"app", "order"},
+		{"env", "prod"},
+		{"region", "cn"},
+		{"host", hostname + "-" + strconv.Itoa(os.Getpid())},
+		{"go", runtime.Version()},
+		{"raw", "${ip}"},
+		{"q", "a=b"},
+	}, fields)
+
+	for _, s := range []string{"app", "=x", "a=${unknown}", "a=${env:LOG_TEST_MISSING}", "a=${pid"} {
+		_, err = parseFields(s)
+		assert.Error(t, err, s)
+	}
+
+	RegisterFieldResolver("zone", func() (string, error) { return "z1", nil })
+	fields, err = parseFields("zone=${zone}")
+	assert.NoError(t, err)
+	assert.Equal(t, [][2]string{{"zone", "z1"}}, fields)
+}
+
+func TestZapLogger_Fields(t *testing.T) {
+	l, buf := newBufferLogger(t, &Config{Fields: "app=order,pid=${pid}"})
+	l.Infof("hello")
+	m := decodeLine(t, buf)
+	assert.Equal(t, "order", m["app"])
+	assert.Equal(t, strconv.Itoa(os.Getpid()), m["pid"])
+
+	_, err := NewLogger(&Config{Fields: "app"})
+	assert.Error(t, err)
+}
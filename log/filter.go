@@ -0,0 +1,306 @@
+package log
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/neura-flow/common/types"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	defaultSamplingInterval = time.Second
+	defaultSamplingFirst    = 100
+	defaultSamplingAfter    = 100
+	defaultRateInterval     = time.Second
+	defaultRateLimit        = 100
+	defaultDedupInterval    = 10 * time.Second
+	// maxFilterKeys 限流记录的 key 超过该数量时清理已过期的 key, 去重记录超过该数量时不再记录新的日志
+	maxFilterKeys = 10000
+)
+
+// DropStats 被丢弃的日志条数
+type DropStats struct {
+	Sampled      uint64 `json:"sampled"`
+	RateLimited  uint64 `json:"rateLimited"`
+	Deduplicated uint64 `json:"deduplicated"`
+}
+
+// DropCounter 支持统计丢弃日志条数的 Logger
+type DropCounter interface {
+	DropStats() DropStats
+}
+
+// filters 根 logger 及其子 logger 共享的采样、限流、去重状态
+type filters struct {
+	cfg         Config
+	sampled     uint64
+	rateLimited uint64
+	dedup       *dedupState
+	rate        *rateState
+}
+
+func newFilters(cfg Config) *filters {
+	f := &filters{cfg: cfg}
+	if cfg.RateLimit.Enabled {
+		f.rate = &rateState{
+			interval: duration(cfg.RateLimit.Interval, defaultRateInterval),
+			limit:    positive(cfg.RateLimit.Limit, defaultRateLimit),
+			key:      cfg.RateLimit.Key,
+			windows:  make(map[string]*rateWindow),
+		}
+	}
+	if cfg.Dedup.Enabled {
+		f.dedup = &dedupState{
+			interval: duration(cfg.Dedup.Interval, defaultDedupInterval),
+			entries:  make(map[dedupKey]*dedupEntry),
+		}
+	}
+	return f
+}
+
+func (f *filters) stats() DropStats {
+	s := DropStats{
+		Sampled:     atomic.LoadUint64(&f.sampled),
+		RateLimited: atomic.LoadUint64(&f.rateLimited),
+	}
+	if f.dedup != nil {
+		s.Deduplicated = atomic.LoadUint64(&f.dedup.dropped)
+	}
+	return s
+}
+
+// wrap 依次应用去重、限流、采样
+func (f *filters) wrap(core zapcore.Core) zapcore.Core {
+	if s := f.cfg.Sampling; s.Enabled {
+		core = zapcore.NewSamplerWithOptions(core,
+			duration(s.Interval, defaultSamplingInterval),
+			positive(s.First, defaultSamplingFirst),
+			positive(s.Thereafter, defaultSamplingAfter),
+			zapcore.SamplerHook(func(_ zapcore.Entry, dec zapcore.SamplingDecision) {
+				if dec&zapcore.LogDropped != 0 {
+					atomic.AddUint64(&f.sampled, 1)
+				}
+			}))
+	}
+	if f.rate != nil {
+		core = &rateLimitCore{Core: core, state: f.rate, dropped: &f.rateLimited}
+	}
+	if f.dedup != nil {
+		core = &dedupCore{Core: core, state: f.dedup}
+	}
+	return core
+}
+
+func duration(d types.Duration, def time.Duration) time.Duration {
+	if d == "" {
+		return def
+	}
+	if v := d.Val(); *v > 0 {
+		return *v
+	}
+	return def
+}
+
+func positive(n, def int) int {
+	if n > 0 {
+		return n
+	}
+	return def
+}
+
+type rateState struct {
+	mu       sync.Mutex
+	interval time.Duration
+	limit    int
+	key      string
+	windows  map[string]*rateWindow
+}
+
+type rateWindow struct {
+	start time.Time
+	count int
+}
+
+// allow 固定窗口限流, 每个 key 每个周期最多输出 limit 条
+func (s *rateState) allow(key string, now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	w, ok := s.windows[key]
+	if !ok || now.Sub(w.start) >= s.interval {
+		if !ok && len(s.windows) >= maxFilterKeys {
+			for k, item := range s.windows {
+				if now.Sub(item.start) >= s.interval {
+					delete(s.windows, k)
+				}
+			}
+		}
+		s.windows[key] = &rateWindow{start: now, count: 1}
+		return true
+	}
+	if w.count >= s.limit {
+		return false
+	}
+	w.count++
+	return true
+}
+
+// rateLimitCore 按日志内容或指定字段的值限流
+type rateLimitCore struct {
+	zapcore.Core
+	state   *rateState
+	dropped *uint64
+}
+
+func (c *rateLimitCore) With(fields []zapcore.Field) zapcore.Core {
+	return &rateLimitCore{Core: c.Core.With(fields), state: c.state, dropped: c.dropped}
+}
+
+func (c *rateLimitCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *rateLimitCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	key := ent.LoggerName + "|" + ent.Message
+	if c.state.key != "" {
+		key = ent.LoggerName + "|" + fieldValue(fields, c.state.key)
+	}
+	if !c.state.allow(key, ent.Time) {
+		atomic.AddUint64(c.dropped, 1)
+		return nil
+	}
+	writeChecked(c.Core, ent, fields)
+	return nil
+}
+
+// writeChecked 经过内层 core 的 Check 后写入, 内层的采样和各输出的级别过滤只在 Check 中生效
+func writeChecked(core zapcore.Core, ent zapcore.Entry, fields []zapcore.Field) {
+	if ce := core.Check(ent, nil); ce != nil {
+		ce.Write(fields...)
+	}
+}
+
+func fieldValue(fields []zapcore.Field, key string) string {
+	for _, f := range fields {
+		if f.Key != key {
+			continue
+		}
+		switch {
+		case f.Type == zapcore.StringType:
+			return f.String
+		case f.Interface != nil:
+			return fmt.Sprint(f.Interface)
+		default:
+			return fmt.Sprint(f.Integer)
+		}
+	}
+	return ""
+}
+
+type dedupKey struct {
+	level   zapcore.Level
+	logger  string
+	message string
+}
+
+type dedupEntry struct {
+	ent   zapcore.Entry
+	core  zapcore.Core
+	count int
+}
+
+type dedupState struct {
+	mu       sync.Mutex
+	interval time.Duration
+	entries  map[dedupKey]*dedupEntry
+	swept    time.Time // 最近一次清理过期日志的时间
+	dropped  uint64
+}
+
+// expired 取出已过周期的日志, all 为 true 时取出所有被合并过的日志
+func (s *dedupState) expired(now time.Time, all bool) []*dedupEntry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sweep(now, all)
+}
+
+// sweep 需持有 mu, 除 all 外每个周期最多遍历一次, 避免内容各不相同的日志使每次写入都遍历所有记录
+func (s *dedupState) sweep(now time.Time, all bool) []*dedupEntry {
+	if !all && now.Sub(s.swept) < s.interval {
+		return nil
+	}
+	s.swept = now
+	var list []*dedupEntry
+	for key, e := range s.entries {
+		if all || now.Sub(e.ent.Time) >= s.interval {
+			delete(s.entries, key)
+			if e.count > 0 {
+				list = append(list, e)
+			}
+		}
+	}
+	return list
+}
+
+// dedupCore 周期内相同级别、相同内容的日志只输出第一条, 周期结束后输出 "(repeated N times)" 汇总.
+// 汇总在之后的日志写入或 Sync 时输出
+type dedupCore struct {
+	zapcore.Core
+	state *dedupState
+}
+
+func (c *dedupCore) With(fields []zapcore.Field) zapcore.Core {
+	return &dedupCore{Core: c.Core.With(fields), state: c.state}
+}
+
+func (c *dedupCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *dedupCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	key := dedupKey{level: ent.Level, logger: ent.LoggerName, message: ent.Message}
+	s := c.state
+	s.mu.Lock()
+	list := s.sweep(ent.Time, false)
+	e, ok := s.entries[key]
+	if ok && ent.Time.Sub(e.ent.Time) < s.interval {
+		e.count++
+		s.mu.Unlock()
+		atomic.AddUint64(&s.dropped, 1)
+		c.flush(list)
+		return nil
+	}
+	if ok && e.count > 0 {
+		list = append(list, e)
+	}
+	// 记录数达到上限时不再记录新的日志, 等待下次清理
+	if ok || len(s.entries) < maxFilterKeys {
+		s.entries[key] = &dedupEntry{ent: ent, core: c.Core}
+	}
+	s.mu.Unlock()
+	c.flush(list)
+	writeChecked(c.Core, ent, fields)
+	return nil
+}
+
+func (c *dedupCore) Sync() error {
+	c.flush(c.state.expired(time.Now(), true))
+	return c.Core.Sync()
+}
+
+func (c *dedupCore) flush(list []*dedupEntry) {
+	for _, e := range list {
+		ent := e.ent
+		ent.Time = time.Now()
+		ent.Message = fmt.Sprintf("%s (repeated %d times)", ent.Message, e.count)
+		writeChecked(e.core, ent, nil)
+	}
+}
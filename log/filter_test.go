@@ -0,0 +1,88 @@
+package log
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestZapLogger_Sampling(t *testing.T) {
+	l, buf := newBufferLogger(t, &Config{Sampling: SamplingConfig{Enabled: true, Interval: "1m", First: 2, Thereafter: 3}})
+	for i := 0; i < 10; i++ {
+		l.Infof("slow query")
+	}
+	// 前 2 条以及之后每 3 条中的 1 条: 1,2,5,8
+	assert.Equal(t, 4, strings.Count(buf.String(), "slow query"))
+	assert.Equal(t, uint64(6), l.DropStats().Sampled)
+}
+
+func TestZapLogger_RateLimit(t *testing.T) {
+	l, buf := newBufferLogger(t, &Config{RateLimit: RateLimitConfig{Enabled: true, Interval: "1m", Limit: 2, Key: "cmd"}})
+	for i := 0; i < 5; i++ {
+		assert.NoError(t, l.Log(LevelInfo, "msg", "slow", "cmd", "get"))
+		assert.NoError(t, l.Log(LevelInfo, "msg", "slow", "cmd", "set"))
+	}
+	assert.Equal(t, 2, strings.Count(buf.String(), `"cmd":"get"`))
+	assert.Equal(t, 2, strings.Count(buf.String(), `"cmd":"set"`))
+	assert.Equal(t, uint64(6), l.DropStats().RateLimited)
+
+	// 子 logger 单独限流, 计数共享
+	l.Named("redis").Infof("x")
+	assert.Contains(t, buf.String(), `"logger":"redis"`)
+}
+
+func TestZapLogger_Dedup(t *testing.T) {
+	l, buf := newBufferLogger(t, &Config{Dedup: DedupConfig{Enabled: true, Interval: "1m"}})
+	for i := 0; i < 4; i++ {
+		l.Warnf("connection refused")
+	}
+	l.Infof("other")
+	assert.Equal(t, 1, strings.Count(buf.String(), "connection refused"))
+	assert.Equal(t, uint64(3), l.DropStats().Deduplicated)
+
+	assert.NoError(t, l.logger.Sync())
+	assert.Contains(t, buf.String(), "connection refused (repeated 3 times)")
+	l.Warnf("connection refused")
+	assert.Equal(t, 3, strings.Count(buf.String(), "connection refused"))
+}
+
+func TestZapLogger_SamplingWithRateLimit(t *testing.T) {
+	l, buf := newBufferLogger(t, &Config{
+		Sampling:  SamplingConfig{Enabled: true, Interval: "1m", First: 2, Thereafter: 3},
+		RateLimit: RateLimitConfig{Enabled: true, Interval: "1m", Limit: 100},
+	})
+	for i := 0; i < 10; i++ {
+		l.Infof("slow query")
+	}
+	// 限流不影响内层的采样
+	assert.Equal(t, 4, strings.Count(buf.String(), "slow query"))
+	assert.Equal(t, uint64(6), l.DropStats().Sampled)
+	assert.Equal(t, uint64(0), l.DropStats().RateLimited)
+}
+
+func TestDedupState_Sweep(t *testing.T) {
+	s := &dedupState{interval: time.Minute, entries: make(map[dedupKey]*dedupEntry)}
+	now := time.Now()
+	s.entries[dedupKey{message: "a"}] = &dedupEntry{ent: zapcore.Entry{Time: now}, count: 1}
+	assert.Len(t, s.expired(now, false), 0)
+	// 一个周期内只清理一次
+	assert.Len(t, s.expired(now.Add(2*time.Minute), false), 1)
+	s.entries[dedupKey{message: "b"}] = &dedupEntry{ent: zapcore.Entry{Time: now}, count: 1}
+	assert.Len(t, s.expired(now.Add(150*time.Second), false), 0)
+	assert.Len(t, s.expired(now.Add(3*time.Minute), false), 1)
+}
+
+func TestZapLogger_DedupMaxKeys(t *testing.T) {
+	l, buf := newBufferLogger(t, &Config{Dedup: DedupConfig{Enabled: true, Interval: "1m"}})
+	for i := 0; i < maxFilterKeys+10; i++ {
+		l.Infof("request %d", i)
+	}
+	// 超过上限的日志不再记录, 但照常输出
+	assert.Len(t, l.filters.dedup.entries, maxFilterKeys)
+	l.Infof("request %d", maxFilterKeys+1)
+	l.Infof("request %d", maxFilterKeys+1)
+	assert.Equal(t, maxFilterKeys+12, strings.Count(buf.String(), "request "))
+}
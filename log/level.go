@@ -0,0 +1,204 @@
+package log
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	jsoniter "github.com/json-iterator/go"
+	"go.uber.org/zap"
+)
+
+// LevelControl 支持运行时修改日志级别的 Logger
+type LevelControl interface {
+	Levels() *LevelManager
+}
+
+// LevelManager 管理根 logger 及其所有 Named 子 logger 的日志级别.
+// 子 logger 使用名称最长匹配的已设置级别, 例如设置 election 为 debug 后, election 与 election.zk 都输出 debug 日志,
+// 未设置时使用根 logger (名称为空) 的级别
+type LevelManager struct {
+	mu       sync.Mutex
+	explicit map[string]Level
+	levels   map[string]zap.AtomicLevel
+}
+
+func newLevelManager(root Level) *LevelManager {
+	if _, ok := zapLevels[root]; !ok {
+		root = LevelInfo
+	}
+	return &LevelManager{
+		explicit: map[string]Level{"": root},
+		levels:   make(map[string]zap.AtomicLevel),
+	}
+}
+
+// register 返回 name 对应的 AtomicLevel, 同名 logger 共享同一个
+func (m *LevelManager) register(name string) zap.AtomicLevel {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if level, ok := m.levels[name]; ok {
+		return level
+	}
+	level := zap.NewAtomicLevelAt(zapLevels[m.effective(name)])
+	m.levels[name] = level
+	return level
+}
+
+// effective 返回 name 的生效级别, 调用方需持有锁
+func (m *LevelManager) effective(name string) Level {
+	for n := name; ; {
+		if level, ok := m.explicit[n]; ok {
+			return level
+		}
+		i := strings.LastIndexByte(n, '.')
+		if i < 0 {
+			if n == "" {
+				return LevelInfo
+			}
+			n = ""
+			continue
+		}
+		n = n[:i]
+	}
+}
+
+// SetLevel 设置 name 及其未单独设置级别的子 logger 的级别, name 为空表示根 logger
+func (m *LevelManager) SetLevel(name string, level Level) error {
+	level = Level(strings.ToLower(string(level)))
+	if _, ok := zapLevels[level]; !ok {
+		return fmt.Errorf("unknown log level: %s", level)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.explicit[name] = level
+	m.refresh()
+	return nil
+}
+
+// ResetLevel 清除 name 单独设置的级别, 之后使用上级 logger 的级别, 根 logger 不能清除
+func (m *LevelManager) ResetLevel(name string) {
+	if name == "" {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.explicit, name)
+	m.refresh()
+}
+
+// Apply 使用 root 及 levels 替换所有已设置的级别, 用于配置重新加载
+func (m *LevelManager) Apply(root Level, levels map[string]Level) error {
+	explicit := map[string]Level{"": LevelInfo}
+	if root != "" {
+		explicit[""] = Level(strings.ToLower(string(root)))
+	}
+	for name, level := range levels {
+		explicit[name] = Level(strings.ToLower(string(level)))
+	}
+	for name, level := range explicit {
+		if _, ok := zapLevels[level]; !ok {
+			return fmt.Errorf("unknown log level %q for logger %q", level, name)
+		}
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.explicit = explicit
+	m.refresh()
+	return nil
+}
+
+func (m *LevelManager) refresh() {
+	for name, level := range m.levels {
+		level.SetLevel(zapLevels[m.effective(name)])
+	}
+}
+
+// Level 返回 name 的生效级别
+func (m *LevelManager) Level(name string) Level {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.effective(name)
+}
+
+// Levels 返回所有已创建的 logger 以及已设置级别的名称对应的生效级别
+func (m *LevelManager) Levels() map[string]Level {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	ret := make(map[string]Level, len(m.levels)+len(m.explicit))
+	for name := range m.levels {
+		ret[name] = m.effective(name)
+	}
+	for name, level := range m.explicit {
+		ret[name] = level
+	}
+	return ret
+}
+
+// OnChange 配置变化时重新设置级别, 参数为 log 配置子树(map), 可直接订阅配置变化, 例如:
+//
+//	cfg.Subscribe("log", logger.(log.LevelControl).Levels().OnChange)
+//
+// 对应的配置:
+//
+//	log:
+//	  level: info
+//	  levels:
+//	    election: debug
+func (m *LevelManager) OnChange(_, v interface{}) {
+	buf, err := jsoniter.Marshal(v)
+	if err != nil {
+		return
+	}
+	cfg := &Config{}
+	if err = jsoniter.Unmarshal(buf, cfg); err != nil {
+		return
+	}
+	_ = m.Apply(cfg.Level, cfg.Levels)
+}
+
+type levelRequest struct {
+	Name  string `json:"name"`
+	Level Level  `json:"level"`
+}
+
+// Handler 查看或修改日志级别:
+//
+//	GET  返回所有 logger 的生效级别, 可通过 query 参数 name 查询单个 logger
+//	PUT  {"name": "election", "level": "debug"}, level 为空时清除单独设置的级别
+func (m *LevelManager) Handler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			if names, ok := r.URL.Query()["name"]; ok {
+				name := names[0]
+				writeJSON(w, http.StatusOK, levelRequest{Name: name, Level: m.Level(name)})
+				return
+			}
+			writeJSON(w, http.StatusOK, m.Levels())
+		case http.MethodPut:
+			req := &levelRequest{}
+			if err := jsoniter.NewDecoder(r.Body).Decode(req); err != nil {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+				return
+			}
+			if req.Level == "" {
+				m.ResetLevel(req.Name)
+			} else if err := m.SetLevel(req.Name, req.Level); err != nil {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+				return
+			}
+			writeJSON(w, http.StatusOK, levelRequest{Name: req.Name, Level: m.Level(req.Name)})
+		default:
+			w.Header().Set("Allow", "GET, PUT")
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(code)
+	_ = jsoniter.NewEncoder(w).Encode(v)
+}
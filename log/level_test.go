@@ -0,0 +1,69 @@
+package log
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestLevelManager(t *testing.T) {
+	logger, err := NewLogger(&Config{Level: LevelInfo, Levels: map[string]Level{"db": LevelError}})
+	assert.NoError(t, err)
+	m := logger.(LevelControl).Levels()
+
+	zk := logger.Named("election").Named("zk").(*ZapLogger)
+	db := logger.Named("db").(*ZapLogger)
+	assert.Equal(t, "election.zk", zk.name)
+	assert.False(t, zk.level.Enabled(zap.DebugLevel))
+	assert.False(t, db.level.Enabled(zap.WarnLevel))
+
+	assert.NoError(t, m.SetLevel("election", LevelDebug))
+	assert.True(t, zk.level.Enabled(zap.DebugLevel))
+	assert.False(t, logger.(*ZapLogger).level.Enabled(zap.DebugLevel))
+	assert.True(t, zk.IsLevel(LevelDebug))
+
+	// 同名 logger 共享级别
+	assert.True(t, logger.Named("election").(*ZapLogger).level.Enabled(zap.DebugLevel))
+
+	m.ResetLevel("election")
+	assert.False(t, zk.level.Enabled(zap.DebugLevel))
+	assert.Error(t, m.SetLevel("db", "verbose"))
+
+	m.OnChange(nil, map[string]interface{}{
+		"level":  "warn",
+		"levels": map[string]interface{}{"election.zk": "debug"},
+	})
+	assert.True(t, zk.level.Enabled(zap.DebugLevel))
+	assert.False(t, db.level.Enabled(zap.InfoLevel))
+	assert.True(t, db.level.Enabled(zap.WarnLevel))
+}
+
+func TestLevelManager_Handler(t *testing.T) {
+	logger, err := NewLogger(&Config{})
+	assert.NoError(t, err)
+	logger.Named("election")
+	h := logger.(LevelControl).Levels().Handler()
+
+	w := httptest.NewRecorder()
+	h(w, httptest.NewRequest(http.MethodPut, "/loggers", strings.NewReader(`{"name":"election","level":"DEBUG"}`)))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"name":"election","level":"debug"}`, w.Body.String())
+
+	w = httptest.NewRecorder()
+	h(w, httptest.NewRequest(http.MethodGet, "/loggers", nil))
+	assert.JSONEq(t, `{"":"info","election":"debug"}`, w.Body.String())
+
+	w = httptest.NewRecorder()
+	h(w, httptest.NewRequest(http.MethodPut, "/loggers", strings.NewReader(`{"name":"election","level":"verbose"}`)))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = httptest.NewRecorder()
+	h(w, httptest.NewRequest(http.MethodPut, "/loggers", strings.NewReader(`{"name":"election"}`)))
+	w = httptest.NewRecorder()
+	h(w, httptest.NewRequest(http.MethodGet, "/loggers?name=election", nil))
+	assert.JSONEq(t, `{"name":"election","level":"info"}`, w.Body.String())
+}
@@ -29,6 +29,8 @@ type Logger interface {
 	Config() Config
 	WithOptions(opts ...Option) Logger
 	With(keyValues ...interface{}) Logger
+	// Named 返回名称为 name 的子 logger, 多级名称用 '.' 连接, 级别可通过 LevelControl 单独设置
+	Named(name string) Logger
 	Log(level Level, kvs ...interface{}) error
 	Debugf(format string, v ...interface{})
 	Warnf(format string, v ...interface{})
@@ -36,6 +38,15 @@ type Logger interface {
 	Errorf(format string, v ...interface{})
 	Fatalf(format string, v ...interface{})
 	Panicf(format string, v ...interface{})
+	// LogCtx 及以下 *Ctx 方法会附加 context 中的 metadata 以及 trace 信息, 见 ContextFields
+	LogCtx(ctx context.Context, level Level, kvs ...interface{}) error
+	DebugCtx(ctx context.Context, format string, v ...interface{})
+	InfoCtx(ctx context.Context, format string, v ...interface{})
+	WarnCtx(ctx context.Context, format string, v ...interface{})
+	ErrorCtx(ctx context.Context, format string, v ...interface{})
+	// Sync 输出所有缓冲的日志, Close 在 Sync 之后释放异步队列、文件等资源, 用于优雅退出
+	Sync() error
+	Close() error
 }
 
 func NewLogger(cfg *Config) (Logger, error) {
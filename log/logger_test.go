@@ -1,8 +1,11 @@
 package log
 
 import (
+	"fmt"
+	"runtime"
 	"testing"
 
+	"github.com/neura-flow/common/metadata"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -34,3 +37,25 @@ func TestLevel_LowerThan(t *testing.T) {
 	assert.True(t, l.LowerThan(l2))
 	assert.False(t, l.LowerThan(l1))
 }
+
+func logFromHelper(l Logger) {
+	l.Infof("from helper")
+}
+
+func TestZapLogger_SkipKept(t *testing.T) {
+	l, buf := newBufferLogger(t, &Config{Caller: CallerConfig{Enabled: true}})
+	skipped := l.WithOptions(WithSkip(1))
+	// Named 以及之后添加字段不会丢失之前的 skip
+	for _, logger := range []Logger{
+		skipped,
+		skipped.Named("sub"),
+		skipped.WithOptions(WithFields(metadata.NewKV("k", "v"))),
+		skipped.Named("sub").WithOptions(WithFields(metadata.NewKV("k", "v"))),
+	} {
+		_, _, line, _ := runtime.Caller(0)
+		logFromHelper(logger)
+		m := decodeLine(t, buf)
+		assert.Equal(t, "from helper", m["message"])
+		assert.Contains(t, m["caller"], fmt.Sprintf("log/logger_test.go:%d", line+1))
+	}
+}
@@ -0,0 +1,76 @@
+package log
+
+import (
+	"context"
+	"runtime"
+	"time"
+
+	"github.com/go-logr/logr"
+)
+
+// logrSink 把 logr 的日志输出到 Logger, V(0) 输出为 info, V(1) 及以上输出为 debug
+type logrSink struct {
+	logger    Logger
+	kvs       []interface{}
+	callDepth int
+}
+
+// NewLogr 返回输出到 logger 的 logr.Logger, 例如:
+//
+//	ctrl.SetLogger(log.NewLogr(logger.Named("controller")))
+func NewLogr(logger Logger) logr.Logger {
+	return logr.New(&logrSink{logger: logger})
+}
+
+func (s *logrSink) Init(info logr.RuntimeInfo) {
+	s.callDepth += info.CallDepth
+}
+
+func logrLevel(level int) Level {
+	if level > 0 {
+		return LevelDebug
+	}
+	return LevelInfo
+}
+
+func (s *logrSink) Enabled(level int) bool {
+	return enabled(s.logger, logrLevel(level))
+}
+
+func (s *logrSink) Info(level int, msg string, kvs ...interface{}) {
+	s.log(logrLevel(level), msg, kvs)
+}
+
+func (s *logrSink) Error(err error, msg string, kvs ...interface{}) {
+	s.log(LevelError, msg, append([]interface{}{fieldKeyError, err}, kvs...))
+}
+
+// log 以调用 logr.Logger 的位置作为调用者输出日志
+func (s *logrSink) log(level Level, msg string, kvs []interface{}) {
+	var pcs [1]uintptr
+	// 跳过 runtime.Callers、log 以及 Info 或 Error
+	runtime.Callers(s.callDepth+3, pcs[:])
+	all := make([]interface{}, 0, len(s.kvs)+len(kvs))
+	all = append(append(all, s.kvs...), kvs...)
+	logAt(context.Background(), s.logger, pcs[0], time.Now(), level, msg, all)
+}
+
+func (s *logrSink) WithValues(kvs ...interface{}) logr.LogSink {
+	c := *s
+	c.kvs = append(append([]interface{}(nil), s.kvs...), kvs...)
+	return &c
+}
+
+func (s *logrSink) WithName(name string) logr.LogSink {
+	c := *s
+	c.logger = s.logger.Named(name)
+	return &c
+}
+
+func (s *logrSink) WithCallDepth(depth int) logr.LogSink {
+	c := *s
+	c.callDepth += depth
+	return &c
+}
+
+var _ logr.CallDepthLogSink = (*logrSink)(nil)
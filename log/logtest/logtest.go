@@ -0,0 +1,309 @@
+// Package logtest 提供记录日志的内存 log.Logger, 用于在测试中断言日志输出, 例如:
+//
+//	logger := logtest.New()
+//	hook := redis.NewHook(cfg, logger)
+//	...
+//	logger.AssertLogged(t, log.LevelWarn, "RedisSlowLog")
+package logtest
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/neura-flow/common/log"
+	"github.com/neura-flow/common/metadata"
+	"github.com/stretchr/testify/assert"
+)
+
+// Entry 一条记录的日志
+type Entry struct {
+	Time    time.Time
+	Level   log.Level
+	Logger  string // Named 设置的名称
+	Message string
+	Fields  map[string]interface{}
+	Caller  string // 调用日志方法的位置, 格式为 file:line
+}
+
+// String 返回便于在断言失败时阅读的格式
+func (e Entry) String() string {
+	return fmt.Sprintf("[%s] %s %s %v (%s)", e.Level, e.Logger, e.Message, e.Fields, e.Caller)
+}
+
+// recorder 根 logger 及其子 logger 共享的日志记录
+type recorder struct {
+	mu      sync.Mutex
+	entries []Entry
+}
+
+// Logger 把所有级别的日志记录在内存中的 log.Logger, 根 logger 及其子 logger 共享同一份记录
+type Logger struct {
+	rec    *recorder
+	name   string
+	fields []metadata.KV
+	skip   int
+}
+
+var _ log.Logger = (*Logger)(nil)
+
+// New 创建 Logger
+func New() *Logger {
+	return &Logger{rec: &recorder{}}
+}
+
+func (l *Logger) clone() *Logger {
+	c := *l
+	c.fields = append([]metadata.KV(nil), l.fields...)
+	return &c
+}
+
+func (l *Logger) Config() log.Config {
+	return log.Config{Level: log.LevelDebug}
+}
+
+func (l *Logger) WithOptions(opts ...log.Option) log.Logger {
+	options := &log.Options{}
+	for _, o := range opts {
+		o(options)
+	}
+	c := l.clone()
+	c.skip += options.Skip
+	c.fields = append(c.fields, options.Fields...)
+	return c
+}
+
+func (l *Logger) With(keyValues ...interface{}) log.Logger {
+	c := l.clone()
+	for i := 0; i+1 < len(keyValues); i += 2 {
+		if key, ok := keyValues[i].(string); ok {
+			c.fields = append(c.fields, metadata.NewKV(key, keyValues[i+1]))
+		}
+	}
+	return c
+}
+
+func (l *Logger) Named(name string) log.Logger {
+	c := l.clone()
+	if c.name == "" {
+		c.name = name
+	} else if name != "" {
+		c.name += "." + name
+	}
+	return c
+}
+
+// record 记录一条日志, depth 为调用方相对于 record 的栈深度
+func (l *Logger) record(depth int, level log.Level, msg string, extra []metadata.KV) {
+	e := Entry{
+		Time:    time.Now(),
+		Level:   level,
+		Logger:  l.name,
+		Message: msg,
+		Fields:  make(map[string]interface{}, len(l.fields)+len(extra)),
+	}
+	for _, kv := range l.fields {
+		e.Fields[kv.Key()] = kv.Value()
+	}
+	for _, kv := range extra {
+		e.Fields[kv.Key()] = kv.Value()
+	}
+	if _, file, line, ok := runtime.Caller(depth + 1 + l.skip); ok {
+		e.Caller = fmt.Sprintf("%s:%d", file, line)
+	}
+	l.rec.mu.Lock()
+	l.rec.entries = append(l.rec.entries, e)
+	l.rec.mu.Unlock()
+}
+
+// kvFields 按 log.Logger.Log 的规则解析 kvs, msg 或 message 作为日志内容
+func kvFields(kvs []interface{}) (string, []metadata.KV, error) {
+	if len(kvs) == 0 || len(kvs)%2 != 0 {
+		return "", nil, fmt.Errorf("kvs must appear in pairs: %v", kvs)
+	}
+	msg := ""
+	var fields []metadata.KV
+	for i := 0; i < len(kvs); i += 2 {
+		key, ok := kvs[i].(string)
+		if !ok {
+			continue
+		}
+		if key == "msg" || key == "message" {
+			msg = fmt.Sprint(kvs[i+1])
+		} else {
+			fields = append(fields, metadata.NewKV(key, kvs[i+1]))
+		}
+	}
+	return msg, fields, nil
+}
+
+func (l *Logger) Log(level log.Level, kvs ...interface{}) error {
+	msg, fields, err := kvFields(kvs)
+	if err != nil {
+		return err
+	}
+	l.record(1, level, msg, fields)
+	return nil
+}
+
+func (l *Logger) LogCtx(ctx context.Context, level log.Level, kvs ...interface{}) error {
+	msg, fields, err := kvFields(kvs)
+	if err != nil {
+		return err
+	}
+	l.record(1, level, msg, append(log.ContextFields(ctx), fields...))
+	return nil
+}
+
+func (l *Logger) Debugf(format string, v ...interface{}) {
+	l.record(1, log.LevelDebug, fmt.Sprintf(format, v...), nil)
+}
+
+func (l *Logger) Infof(format string, v ...interface{}) {
+	l.record(1, log.LevelInfo, fmt.Sprintf(format, v...), nil)
+}
+
+func (l *Logger) Warnf(format string, v ...interface{}) {
+	l.record(1, log.LevelWarn, fmt.Sprintf(format, v...), nil)
+}
+
+func (l *Logger) Errorf(format string, v ...interface{}) {
+	l.record(1, log.LevelError, fmt.Sprintf(format, v...), nil)
+}
+
+// Fatalf 只记录日志, 不会退出程序
+func (l *Logger) Fatalf(format string, v ...interface{}) {
+	l.record(1, log.LevelFatal, fmt.Sprintf(format, v...), nil)
+}
+
+// Panicf 记录日志后 panic
+func (l *Logger) Panicf(format string, v ...interface{}) {
+	msg := fmt.Sprintf(format, v...)
+	l.record(1, log.LevelPanic, msg, nil)
+	panic(msg)
+}
+
+func (l *Logger) DebugCtx(ctx context.Context, format string, v ...interface{}) {
+	l.record(1, log.LevelDebug, fmt.Sprintf(format, v...), log.ContextFields(ctx))
+}
+
+func (l *Logger) InfoCtx(ctx context.Context, format string, v ...interface{}) {
+	l.record(1, log.LevelInfo, fmt.Sprintf(format, v...), log.ContextFields(ctx))
+}
+
+func (l *Logger) WarnCtx(ctx context.Context, format string, v ...interface{}) {
+	l.record(1, log.LevelWarn, fmt.Sprintf(format, v...), log.ContextFields(ctx))
+}
+
+func (l *Logger) ErrorCtx(ctx context.Context, format string, v ...interface{}) {
+	l.record(1, log.LevelError, fmt.Sprintf(format, v...), log.ContextFields(ctx))
+}
+
+func (l *Logger) Sync() error {
+	return nil
+}
+
+func (l *Logger) Close() error {
+	return nil
+}
+
+// Entries 返回已记录的全部日志
+func (l *Logger) Entries() []Entry {
+	l.rec.mu.Lock()
+	defer l.rec.mu.Unlock()
+	return append([]Entry(nil), l.rec.entries...)
+}
+
+// Len 返回已记录的日志条数
+func (l *Logger) Len() int {
+	l.rec.mu.Lock()
+	defer l.rec.mu.Unlock()
+	return len(l.rec.entries)
+}
+
+// Reset 清空已记录的日志
+func (l *Logger) Reset() {
+	l.rec.mu.Lock()
+	defer l.rec.mu.Unlock()
+	l.rec.entries = nil
+}
+
+// Filter 返回满足 fn 的日志
+func (l *Logger) Filter(fn func(Entry) bool) []Entry {
+	var list []Entry
+	for _, e := range l.Entries() {
+		if fn(e) {
+			list = append(list, e)
+		}
+	}
+	return list
+}
+
+// FilterLevel 返回级别为 level 的日志
+func (l *Logger) FilterLevel(level log.Level) []Entry {
+	return l.Filter(func(e Entry) bool {
+		return e.Level == level
+	})
+}
+
+// FilterMessage 返回内容包含 substring 的日志
+func (l *Logger) FilterMessage(substring string) []Entry {
+	return l.Filter(func(e Entry) bool {
+		return strings.Contains(e.Message, substring)
+	})
+}
+
+// FilterField 返回字段 key 的值等于 value 的日志
+func (l *Logger) FilterField(key string, value interface{}) []Entry {
+	return l.Filter(func(e Entry) bool {
+		v, ok := e.Fields[key]
+		return ok && assert.ObjectsAreEqual(value, v)
+	})
+}
+
+// FilterLogger 返回名称为 name 的 logger 输出的日志
+func (l *Logger) FilterLogger(name string) []Entry {
+	return l.Filter(func(e Entry) bool {
+		return e.Logger == name
+	})
+}
+
+func (l *Logger) logged(level log.Level, substring string) bool {
+	return len(l.Filter(func(e Entry) bool {
+		return e.Level == level && strings.Contains(e.Message, substring)
+	})) > 0
+}
+
+// AssertLogged 断言记录了级别为 level 且内容包含 substring 的日志
+func (l *Logger) AssertLogged(t assert.TestingT, level log.Level, substring string) bool {
+	if h, ok := t.(interface{ Helper() }); ok {
+		h.Helper()
+	}
+	if l.logged(level, substring) {
+		return true
+	}
+	return assert.Fail(t, fmt.Sprintf("no %s log containing %q, logged:\n%s", level, substring, l.dump()))
+}
+
+// AssertNotLogged 断言没有记录级别为 level 且内容包含 substring 的日志
+func (l *Logger) AssertNotLogged(t assert.TestingT, level log.Level, substring string) bool {
+	if h, ok := t.(interface{ Helper() }); ok {
+		h.Helper()
+	}
+	if !l.logged(level, substring) {
+		return true
+	}
+	return assert.Fail(t, fmt.Sprintf("unexpected %s log containing %q, logged:\n%s", level, substring, l.dump()))
+}
+
+func (l *Logger) dump() string {
+	var b strings.Builder
+	for _, e := range l.Entries() {
+		b.WriteString(e.String())
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
@@ -0,0 +1,52 @@
+package logtest
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/neura-flow/common/log"
+	"github.com/neura-flow/common/metadata"
+	"github.com/stretchr/testify/assert"
+)
+
+type mockT struct {
+	msg string
+}
+
+func (m *mockT) Errorf(format string, args ...interface{}) {
+	m.msg = fmt.Sprintf(format, args...)
+}
+
+func TestLogger(t *testing.T) {
+	logger := New()
+	logger.Infof("hello %s", "world")
+	sub := logger.Named("election").With("node", "n1")
+	sub.Warnf("lost leadership")
+	ctx := metadata.ToContext(context.Background(), metadata.FromMap(map[string]interface{}{metadata.KeyTraceId: "t1"}))
+	assert.NoError(t, sub.LogCtx(ctx, log.LevelError, "msg", "campaign failed", "attempt", 3))
+	assert.Error(t, sub.Log(log.LevelInfo, "msg"))
+
+	assert.Equal(t, 3, logger.Len())
+	logger.AssertLogged(t, log.LevelInfo, "hello world")
+	logger.AssertLogged(t, log.LevelWarn, "lost")
+	logger.AssertNotLogged(t, log.LevelError, "hello")
+
+	e := logger.FilterLevel(log.LevelError)[0]
+	assert.Equal(t, "election", e.Logger)
+	assert.Equal(t, "campaign failed", e.Message)
+	assert.Equal(t, map[string]interface{}{"node": "n1", "attempt": 3, metadata.KeyTraceId: "t1"}, e.Fields)
+	assert.Contains(t, e.Caller, "logtest_test.go:27")
+
+	assert.Len(t, logger.FilterField("node", "n1"), 2)
+	assert.Len(t, logger.FilterLogger("election"), 2)
+	assert.Len(t, logger.FilterMessage("hello"), 1)
+
+	mock := &mockT{}
+	assert.False(t, logger.AssertLogged(mock, log.LevelDebug, "hello"))
+	assert.Contains(t, mock.msg, "hello world")
+
+	assert.Panics(t, func() { logger.Panicf("boom") })
+	logger.Reset()
+	assert.Equal(t, 0, logger.Len())
+}
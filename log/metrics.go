@@ -0,0 +1,46 @@
+package log
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	descQueueDepth = prometheus.NewDesc("log_async_queue_depth",
+		"Number of log entries waiting in the async queue.", nil, nil)
+	descQueueCapacity = prometheus.NewDesc("log_async_queue_capacity",
+		"Capacity of the async log queue.", nil, nil)
+	descDropped = prometheus.NewDesc("log_dropped_total",
+		"Number of log entries dropped.", []string{"reason"}, nil)
+)
+
+type collector struct {
+	logger Logger
+}
+
+// NewCollector 返回 logger 的 prometheus 指标: 异步队列深度、容量以及因采样、限流、去重、队列满被丢弃的日志条数, 例如:
+//
+//	prometheus.MustRegister(log.NewCollector(logger))
+func NewCollector(logger Logger) prometheus.Collector {
+	return &collector{logger: logger}
+}
+
+func (c *collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- descQueueDepth
+	ch <- descQueueCapacity
+	ch <- descDropped
+}
+
+func (c *collector) Collect(ch chan<- prometheus.Metric) {
+	if l, ok := c.logger.(interface{ AsyncStats() AsyncStats }); ok {
+		s := l.AsyncStats()
+		ch <- prometheus.MustNewConstMetric(descQueueDepth, prometheus.GaugeValue, float64(s.Depth))
+		ch <- prometheus.MustNewConstMetric(descQueueCapacity, prometheus.GaugeValue, float64(s.Capacity))
+		ch <- prometheus.MustNewConstMetric(descDropped, prometheus.CounterValue, float64(s.Dropped), "queue_full")
+	}
+	if l, ok := c.logger.(DropCounter); ok {
+		s := l.DropStats()
+		ch <- prometheus.MustNewConstMetric(descDropped, prometheus.CounterValue, float64(s.Sampled), "sampled")
+		ch <- prometheus.MustNewConstMetric(descDropped, prometheus.CounterValue, float64(s.RateLimited), "rate_limited")
+		ch <- prometheus.MustNewConstMetric(descDropped, prometheus.CounterValue, float64(s.Deduplicated), "deduplicated")
+	}
+}
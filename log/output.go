@@ -0,0 +1,131 @@
+package log
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// 内置的输出类型
+const (
+	OutputFile   = "file"
+	OutputSyslog = "syslog"
+	OutputTCP    = "tcp"
+	OutputUDP    = "udp"
+	OutputHTTP   = "http"
+)
+
+// Output 除 std/file 外的日志输出, 每个输出有独立的级别和格式
+type Output interface {
+	io.Closer
+	// Core 返回使用 enc 编码、按 level 过滤的 core, 每个 Named 子 logger 都会调用一次
+	Core(enc zapcore.Encoder, level zapcore.LevelEnabler) zapcore.Core
+}
+
+// OutputFactory 根据配置创建 Output
+type OutputFactory func(cfg OutputConfig) (Output, error)
+
+var (
+	outputsMu sync.RWMutex
+	outputs   = map[string]OutputFactory{}
+)
+
+func init() {
+	RegisterOutput(OutputFile, newRotateOutput)
+	RegisterOutput(OutputSyslog, newSyslogOutput)
+	RegisterOutput(OutputTCP, newShipperOutput)
+	RegisterOutput(OutputUDP, newShipperOutput)
+	RegisterOutput(OutputHTTP, newShipperOutput)
+}
+
+// RegisterOutput 注册自定义输出类型, 同名的会被替换
+func RegisterOutput(typ string, factory OutputFactory) {
+	outputsMu.Lock()
+	defer outputsMu.Unlock()
+	outputs[typ] = factory
+}
+
+// writerOutput 输出到 WriteSyncer 的 Output
+type writerOutput struct {
+	zapcore.WriteSyncer
+	closer io.Closer
+}
+
+func (o *writerOutput) Core(enc zapcore.Encoder, level zapcore.LevelEnabler) zapcore.Core {
+	return zapcore.NewCore(enc, o.WriteSyncer, level)
+}
+
+func (o *writerOutput) Close() error {
+	if o.closer == nil {
+		return nil
+	}
+	return o.closer.Close()
+}
+
+// output 已创建的 Output 及其编码和最低级别
+type output struct {
+	Output
+	encoder zapcore.Encoder
+	min     zapcore.Level
+}
+
+// core 返回同时满足 logger 级别和输出最低级别的 core
+func (o *output) core(level zapcore.LevelEnabler) zapcore.Core {
+	return o.Core(o.encoder.Clone(), zap.LevelEnablerFunc(func(l zapcore.Level) bool {
+		return l >= o.min && level.Enabled(l)
+	}))
+}
+
+// initOutputs 创建 cfg.Outputs 中配置的输出, 任一失败时关闭已创建的输出
+func (l *ZapLogger) initOutputs() error {
+	for _, cfg := range l.cfg.Outputs {
+		outputsMu.RLock()
+		factory, ok := outputs[cfg.Type]
+		outputsMu.RUnlock()
+		if !ok {
+			l.closeOutputs()
+			return fmt.Errorf("unknown log output type: %s", cfg.Type)
+		}
+		o, err := factory(cfg)
+		if err != nil {
+			l.closeOutputs()
+			return fmt.Errorf("failed to create log output %s: %w", cfg.Type, err)
+		}
+		min := zapcore.DebugLevel
+		if cfg.Level != "" {
+			if min, ok = zapLevels[Level(strings.ToLower(string(cfg.Level)))]; !ok {
+				_ = o.Close()
+				l.closeOutputs()
+				return fmt.Errorf("unknown log level %q for output %s", cfg.Level, cfg.Type)
+			}
+		}
+		encoding := cfg.Encoding
+		if encoding == "" {
+			encoding = l.cfg.Encoding
+		}
+		l.outputs = append(l.outputs, &output{Output: o, encoder: l.newEncoder(encoding), min: min})
+	}
+	return nil
+}
+
+func (l *ZapLogger) closeOutputs() error {
+	var err error
+	for _, o := range l.outputs {
+		if e := o.Close(); err == nil {
+			err = e
+		}
+	}
+	l.outputs = nil
+	return err
+}
+
+func (l *ZapLogger) newEncoder(encoding string) zapcore.Encoder {
+	if encoding == EncodingJSON {
+		return zapcore.NewJSONEncoder(l.encoderConf)
+	}
+	return zapcore.NewConsoleEncoder(l.encoderConf)
+}
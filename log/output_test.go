@@ -0,0 +1,229 @@
+package log
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/neura-flow/common/config"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestRotateFile(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2024, 3, 1, 10, 30, 0, 0, time.Local)
+	old := filepath.Join(dir, "app.2024-02-27T10.log")
+	assert.NoError(t, os.WriteFile(old, []byte("old\n"), 0644))
+	assert.NoError(t, os.Chtimes(old, now.Add(-48*time.Hour), now.Add(-48*time.Hour)))
+	// 其他输出及无关的文件不会被删除
+	others := []string{
+		filepath.Join(dir, "app.error.2024-02-27T10.log"),
+		filepath.Join(dir, "app.2024-02-27.log"),
+		filepath.Join(dir, "app.backup.log"),
+	}
+	for _, other := range others {
+		assert.NoError(t, os.WriteFile(other, []byte("other\n"), 0644))
+		assert.NoError(t, os.Chtimes(other, now.Add(-48*time.Hour), now.Add(-48*time.Hour)))
+	}
+
+	f, err := newRotateFile(filepath.Join(dir, "app.log"), RotationHourly, 24*time.Hour)
+	assert.NoError(t, err)
+	f.now = func() time.Time { return now }
+	_, _ = f.Write([]byte("a\n"))
+	_, _ = f.Write([]byte("b\n"))
+	now = now.Add(time.Hour)
+	_, _ = f.Write([]byte("c\n"))
+	assert.NoError(t, f.Close())
+
+	buf, err := os.ReadFile(filepath.Join(dir, "app.2024-03-01T10.log"))
+	assert.NoError(t, err)
+	assert.Equal(t, "a\nb\n", string(buf))
+	buf, err = os.ReadFile(filepath.Join(dir, "app.2024-03-01T11.log"))
+	assert.NoError(t, err)
+	assert.Equal(t, "c\n", string(buf))
+	_, err = os.Stat(old)
+	assert.True(t, os.IsNotExist(err))
+	for _, other := range others {
+		_, err = os.Stat(other)
+		assert.NoError(t, err)
+	}
+
+	_, err = newRotateFile(filepath.Join(dir, "app.log"), "weekly", 0)
+	assert.Error(t, err)
+}
+
+func TestSyslogOutput(t *testing.T) {
+	// unix socket 路径长度有限, 不使用 t.TempDir
+	dir, err := os.MkdirTemp("", "syslog")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	addr := filepath.Join(dir, "log.sock")
+	conn, err := net.ListenPacket("unixgram", addr)
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	logger, err := NewLogger(&Config{
+		Outputs: []OutputConfig{{Type: OutputSyslog, Address: addr, Tag: "app", Level: LevelWarn, Facility: 16}},
+	})
+	assert.NoError(t, err)
+	logger.Infof("ignored")
+	logger.Warnf("disk %s", "full")
+
+	buf := make([]byte, 4096)
+	assert.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+	n, _, err := conn.ReadFrom(buf)
+	assert.NoError(t, err)
+	msg := string(buf[:n])
+	// local0(16) * 8 + warning(4)
+	assert.True(t, strings.HasPrefix(msg, "<132>1 "), msg)
+	assert.Contains(t, msg, " app ")
+	assert.Contains(t, msg, `"message":"disk full"`)
+	assert.NoError(t, logger.Close())
+}
+
+func TestShipperOutput_TCP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer ln.Close()
+	lines := make(chan string, 10)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		scanner := bufio.NewScanner(conn)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+	}()
+
+	logger, err := NewLogger(&Config{
+		Level:   LevelDebug,
+		Outputs: []OutputConfig{{Type: OutputTCP, Address: ln.Addr().String(), Encoding: EncodingConsole, FlushInterval: "1h"}},
+	})
+	assert.NoError(t, err)
+	logger.Debugf("first")
+	logger.Infof("second")
+	assert.NoError(t, logger.Sync())
+	for _, want := range []string{"first", "second"} {
+		select {
+		case line := <-lines:
+			assert.Contains(t, line, want)
+			assert.False(t, json.Valid([]byte(line)))
+		case <-time.After(time.Second):
+			t.Fatal("timeout")
+		}
+	}
+	assert.NoError(t, logger.Close())
+}
+
+func TestShipperOutput_HTTP(t *testing.T) {
+	var mu sync.Mutex
+	var received []map[string]interface{}
+	var calls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+		if calls == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		var batch []map[string]interface{}
+		assert.NoError(t, json.Unmarshal(body, &batch))
+		received = append(received, batch...)
+	}))
+	defer srv.Close()
+
+	logger, err := NewLogger(&Config{
+		Outputs: []OutputConfig{{Type: OutputHTTP, Address: srv.URL, BatchSize: 2, FlushInterval: "1h"}},
+	})
+	assert.NoError(t, err)
+	for i := 0; i < 3; i++ {
+		logger.Infof("line %d", i)
+	}
+	assert.NoError(t, logger.Close())
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Len(t, received, 3)
+	assert.Equal(t, "line 0", received[0]["message"])
+	assert.Equal(t, 3, calls)
+}
+
+func TestShipper_BufferFull(t *testing.T) {
+	s := newShipper(&netSender{network: OutputTCP, address: "127.0.0.1:0"}, OutputConfig{BufferSize: 2, FlushInterval: "1h"})
+	for i := 0; i < 5; i++ {
+		_, _ = s.Write([]byte("x\n"))
+	}
+	s.mu.Lock()
+	assert.Len(t, s.queue, 2)
+	assert.Equal(t, uint64(3), s.dropped)
+	s.mu.Unlock()
+	close(s.stop)
+	<-s.done
+}
+
+func TestOutputs_LevelWithFilters(t *testing.T) {
+	buf := &bytes.Buffer{}
+	RegisterOutput("test-buffer", func(OutputConfig) (Output, error) {
+		return &writerOutput{WriteSyncer: zapcore.AddSync(buf)}, nil
+	})
+	for _, cfg := range []*Config{
+		{RateLimit: RateLimitConfig{Enabled: true, Interval: "1m", Limit: 100}},
+		{Dedup: DedupConfig{Enabled: true, Interval: "1m"}},
+	} {
+		buf.Reset()
+		cfg.Level = LevelDebug
+		cfg.Outputs = []OutputConfig{{Type: "test-buffer", Level: LevelError}}
+		logger, err := NewLogger(cfg)
+		assert.NoError(t, err)
+		logger.Debugf("debug line")
+		logger.Infof("info line")
+		logger.Errorf("error line")
+		assert.NoError(t, logger.Close())
+		assert.NotContains(t, buf.String(), "debug line")
+		assert.NotContains(t, buf.String(), "info line")
+		assert.Contains(t, buf.String(), "error line")
+	}
+}
+
+func TestOutputs_Invalid(t *testing.T) {
+	_, err := NewLogger(&Config{Outputs: []OutputConfig{{Type: "kafka"}}})
+	assert.Error(t, err)
+	_, err = NewLogger(&Config{Outputs: []OutputConfig{{Type: OutputFile, Path: filepath.Join(t.TempDir(), "a.log"), Level: "verbose"}}})
+	assert.Error(t, err)
+}
+
+func TestOutputs_ConfigValidate(t *testing.T) {
+	// 通过 config 加载时按 validate tag 校验输出配置
+	c, err := config.NewFromYaml([]byte(`log:
+  outputs:
+    - type: kafka
+    - type: file
+      path: /var/log/app.log
+      rotation: hourly
+`))
+	assert.NoError(t, err)
+	var cfg Config
+	assert.NoError(t, c.Dump("log", &cfg))
+	assert.Equal(t, "kafka", cfg.Outputs[0].Type)
+	assert.Equal(t, RotationHourly, cfg.Outputs[1].Rotation)
+
+	c, err = config.NewFromYaml([]byte("log:\n  outputs:\n    - type: file\n      rotation: weekly\n"))
+	assert.NoError(t, err)
+	assert.Error(t, c.Dump("log", &cfg))
+}
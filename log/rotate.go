@@ -0,0 +1,141 @@
+package log
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	RotationHourly = "hourly"
+	RotationDaily  = "daily"
+)
+
+// rotateFile 按小时或天切分的日志文件, 文件名为 path 加上时间, 如 app.log -> app.2006-01-02.log,
+// 切分时删除修改时间超过 maxAge 的旧文件
+type rotateFile struct {
+	mu     sync.Mutex
+	dir    string
+	prefix string // 文件名中时间之前的部分, 如 app.
+	ext    string
+	layout string
+	maxAge time.Duration
+	now    func() time.Time
+
+	file  *os.File
+	stamp string
+}
+
+func newRotateOutput(cfg OutputConfig) (Output, error) {
+	f, err := newRotateFile(cfg.Path, cfg.Rotation, duration(cfg.MaxAge, 0))
+	if err != nil {
+		return nil, err
+	}
+	return &writerOutput{WriteSyncer: f, closer: f}, nil
+}
+
+func newRotateFile(path, rotation string, maxAge time.Duration) (*rotateFile, error) {
+	if path == "" {
+		return nil, errors.New("log file path is required")
+	}
+	layout := "2006-01-02"
+	switch rotation {
+	case RotationHourly:
+		layout = "2006-01-02T15"
+	case RotationDaily, "":
+	default:
+		return nil, errors.New("unknown log rotation: " + rotation)
+	}
+	ext := filepath.Ext(path)
+	f := &rotateFile{
+		dir:    filepath.Dir(path),
+		prefix: strings.TrimSuffix(filepath.Base(path), ext) + ".",
+		ext:    ext,
+		layout: layout,
+		maxAge: maxAge,
+		now:    time.Now,
+	}
+	if err := os.MkdirAll(f.dir, 0755); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *rotateFile) name(stamp string) string {
+	return filepath.Join(f.dir, f.prefix+stamp+f.ext)
+}
+
+func (f *rotateFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	now := f.now()
+	if stamp := now.Format(f.layout); f.file == nil || stamp != f.stamp {
+		if err := f.rotate(stamp, now); err != nil {
+			return 0, err
+		}
+	}
+	return f.file.Write(p)
+}
+
+// rotate 切换到 stamp 对应的文件, 调用方需持有锁
+func (f *rotateFile) rotate(stamp string, now time.Time) error {
+	file, err := os.OpenFile(f.name(stamp), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	if f.file != nil {
+		_ = f.file.Close()
+	}
+	f.file, f.stamp = file, stamp
+	if f.maxAge > 0 {
+		f.cleanup(now)
+	}
+	return nil
+}
+
+func (f *rotateFile) cleanup(now time.Time) {
+	matches, err := filepath.Glob(filepath.Join(f.dir, f.prefix+"*"+f.ext))
+	if err != nil {
+		return
+	}
+	for _, path := range matches {
+		if path == f.name(f.stamp) {
+			continue
+		}
+		// 只删除时间部分符合 layout 的文件, 以免误删同目录下其他输出的文件(如 app.error.2006-01-02.log)
+		stamp := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(path), f.prefix), f.ext)
+		if _, err := time.Parse(f.layout, stamp); err != nil {
+			continue
+		}
+		if info, err := os.Stat(path); err == nil && now.Sub(info.ModTime()) > f.maxAge {
+			_ = os.Remove(path)
+		}
+	}
+}
+
+func (f *rotateFile) Sync() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.file == nil {
+		return nil
+	}
+	return f.file.Sync()
+}
+
+func (f *rotateFile) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	f.file = nil
+	return err
+}
+
+var _ zapcore.WriteSyncer = (*rotateFile)(nil)
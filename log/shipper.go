@@ -0,0 +1,276 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	defaultShipperBatchSize     = 100
+	defaultShipperFlushInterval = time.Second
+	defaultShipperRetries       = 3
+	defaultShipperTimeout       = 5 * time.Second
+	defaultShipperBufferSize    = 10000
+	shipperBackoff              = 100 * time.Millisecond
+)
+
+// sender 发送一批日志, 每条日志不含结尾的换行
+type sender interface {
+	send(lines [][]byte) error
+	close() error
+}
+
+// shipper 缓存日志并由后台 goroutine 按批通过 tcp、udp、http 发送, 失败时重试,
+// 缓存满时丢弃最早的日志
+type shipper struct {
+	sender    sender
+	batchSize int
+	retries   int
+	capacity  int
+
+	mu      sync.Mutex
+	queue   [][]byte
+	dropped uint64
+
+	sendMu sync.Mutex // 保证同一时刻只有一批在发送
+	notify chan struct{}
+	stop   chan struct{}
+	done   chan struct{}
+	once   sync.Once
+}
+
+func newShipperOutput(cfg OutputConfig) (Output, error) {
+	if cfg.Address == "" {
+		return nil, errors.New("log output address is required")
+	}
+	timeout := duration(cfg.Timeout, defaultShipperTimeout)
+	var s sender
+	switch cfg.Type {
+	case OutputTCP, OutputUDP:
+		s = &netSender{network: cfg.Type, address: cfg.Address, timeout: timeout}
+	case OutputHTTP:
+		s = &httpSender{url: cfg.Address, client: &http.Client{Timeout: timeout}}
+	default:
+		return nil, fmt.Errorf("unsupported shipper type: %s", cfg.Type)
+	}
+	sh := newShipper(s, cfg)
+	return &writerOutput{WriteSyncer: sh, closer: sh}, nil
+}
+
+func newShipper(s sender, cfg OutputConfig) *shipper {
+	sh := &shipper{
+		sender:    s,
+		batchSize: positive(cfg.BatchSize, defaultShipperBatchSize),
+		retries:   positive(cfg.Retries, defaultShipperRetries),
+		capacity:  positive(cfg.BufferSize, defaultShipperBufferSize),
+		notify:    make(chan struct{}, 1),
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+	go sh.run(duration(cfg.FlushInterval, defaultShipperFlushInterval))
+	return sh
+}
+
+func (s *shipper) Write(p []byte) (int, error) {
+	b := make([]byte, len(p))
+	copy(b, p)
+	b = bytes.TrimRight(b, "\n")
+	s.mu.Lock()
+	if len(s.queue) >= s.capacity {
+		s.queue[0] = nil
+		s.queue = s.queue[1:]
+		s.dropped++
+	}
+	s.queue = append(s.queue, b)
+	full := len(s.queue) >= s.batchSize
+	s.mu.Unlock()
+	if full {
+		select {
+		case s.notify <- struct{}{}:
+		default:
+		}
+	}
+	return len(p), nil
+}
+
+// Sync 发送缓存中的全部日志, 返回最后一次发送失败的错误
+func (s *shipper) Sync() error {
+	return s.flush()
+}
+
+// Close 发送缓存中的日志后停止后台 goroutine 并关闭连接
+func (s *shipper) Close() error {
+	var err error
+	s.once.Do(func() {
+		close(s.stop)
+		<-s.done
+		err = s.flush()
+		if e := s.sender.close(); err == nil {
+			err = e
+		}
+	})
+	return err
+}
+
+func (s *shipper) run(interval time.Duration) {
+	defer close(s.done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+		case <-s.notify:
+		}
+		_ = s.flush()
+	}
+}
+
+func (s *shipper) flush() error {
+	s.sendMu.Lock()
+	defer s.sendMu.Unlock()
+	var err error
+	for {
+		s.mu.Lock()
+		n := len(s.queue)
+		if n > s.batchSize {
+			n = s.batchSize
+		}
+		batch := s.queue[:n:n]
+		s.queue = s.queue[n:]
+		s.mu.Unlock()
+		if n == 0 {
+			return err
+		}
+		if e := s.send(batch); e != nil {
+			s.mu.Lock()
+			s.dropped += uint64(n)
+			s.mu.Unlock()
+			err = e
+		}
+	}
+}
+
+// send 发送一批日志, 失败时按指数退避重试 retries 次
+func (s *shipper) send(batch [][]byte) error {
+	backoff := shipperBackoff
+	var err error
+	for i := 0; i <= s.retries; i++ {
+		if i > 0 {
+			select {
+			case <-time.After(backoff):
+			case <-s.stop:
+				// 关闭时不再等待, 立即重试
+			}
+			backoff *= 2
+		}
+		if err = s.sender.send(batch); err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+// netSender 通过 tcp 或 udp 发送日志, tcp 每条日志以换行结尾, udp 每条日志一个数据报
+type netSender struct {
+	network string
+	address string
+	timeout time.Duration
+	conn    net.Conn
+}
+
+func (s *netSender) send(lines [][]byte) error {
+	if s.conn == nil {
+		conn, err := net.DialTimeout(s.network, s.address, s.timeout)
+		if err != nil {
+			return err
+		}
+		s.conn = conn
+	}
+	err := s.write(lines)
+	if err != nil {
+		_ = s.conn.Close()
+		s.conn = nil
+	}
+	return err
+}
+
+func (s *netSender) write(lines [][]byte) error {
+	if err := s.conn.SetWriteDeadline(time.Now().Add(s.timeout)); err != nil {
+		return err
+	}
+	if s.network == OutputUDP {
+		for _, line := range lines {
+			if _, err := s.conn.Write(line); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	var b bytes.Buffer
+	for _, line := range lines {
+		b.Write(line)
+		b.WriteByte('\n')
+	}
+	_, err := s.conn.Write(b.Bytes())
+	return err
+}
+
+func (s *netSender) close() error {
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
+
+// httpSender 以 JSON 数组 POST 日志, 非 JSON 格式的日志作为字符串
+type httpSender struct {
+	url    string
+	client *http.Client
+}
+
+func (s *httpSender) send(lines [][]byte) error {
+	var b bytes.Buffer
+	b.WriteByte('[')
+	for i, line := range lines {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		if json.Valid(line) {
+			b.Write(line)
+		} else {
+			v, _ := json.Marshal(string(line))
+			b.Write(v)
+		}
+	}
+	b.WriteByte(']')
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, s.url, &b)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("log shipper: unexpected status %s", resp.Status)
+	}
+	return nil
+}
+
+func (s *httpSender) close() error {
+	s.client.CloseIdleConnections()
+	return nil
+}
@@ -0,0 +1,333 @@
+package log
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"runtime"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// slog 中没有 fatal、panic 级别, 分别使用 error+4、error+8
+const (
+	slogLevelFatal = slog.LevelError + 4
+	slogLevelPanic = slog.LevelError + 8
+)
+
+// fromSlogLevel 把 slog 级别转换为 Level, 高于 error 的级别也作为 error, 避免桥接的日志导致退出
+func fromSlogLevel(l slog.Level) Level {
+	switch {
+	case l < slog.LevelInfo:
+		return LevelDebug
+	case l < slog.LevelWarn:
+		return LevelInfo
+	case l < slog.LevelError:
+		return LevelWarn
+	default:
+		return LevelError
+	}
+}
+
+func toSlogLevel(l Level) slog.Level {
+	switch l {
+	case LevelDebug:
+		return slog.LevelDebug
+	case LevelWarn:
+		return slog.LevelWarn
+	case LevelError:
+		return slog.LevelError
+	case LevelFatal:
+		return slogLevelFatal
+	case LevelPanic:
+		return slogLevelPanic
+	default:
+		return slog.LevelInfo
+	}
+}
+
+// enabled 判断 logger 是否输出 level 级别的日志
+func enabled(l Logger, level Level) bool {
+	if zl, ok := l.(*ZapLogger); ok {
+		return zl.level.Enabled(zapLevels[level])
+	}
+	min := l.Config().Level
+	return min == "" || !level.LowerThan(min)
+}
+
+// logAt 以 pc 所在位置作为调用者输出日志, kvs 中不包含 msg. 非 ZapLogger 时通过 LogCtx 输出
+func logAt(ctx context.Context, l Logger, pc uintptr, t time.Time, level Level, msg string, kvs []interface{}) {
+	zl, ok := l.(*ZapLogger)
+	if !ok {
+		_ = l.LogCtx(ctx, level, append([]interface{}{"msg", msg}, kvs...)...)
+		return
+	}
+	ent := zapcore.Entry{
+		LoggerName: zl.logger.Name(),
+		Time:       t,
+		Level:      zapLevels[level],
+		Message:    msg,
+	}
+	if zl.cfg.Caller.Enabled && pc != 0 {
+		frame, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+		ent.Caller = zapcore.NewEntryCaller(pc, frame.File, frame.Line, true)
+		ent.Caller.Function = frame.Function
+	}
+	ce := zl.logger.Core().Check(ent, nil)
+	if ce == nil {
+		return
+	}
+	fields := contextZapFields(ctx)
+	for i := 0; i+1 < len(kvs); i += 2 {
+		key, ok := kvs[i].(string)
+		if !ok {
+			continue
+		}
+		if err, ok := kvs[i+1].(error); ok {
+			fields = append(fields, ErrorField(key, err))
+		} else {
+			fields = append(fields, zap.Any(key, kvs[i+1]))
+		}
+	}
+	ce.Write(fields...)
+}
+
+// slogHandler 把 slog 的日志输出到 Logger
+type slogHandler struct {
+	logger Logger
+	prefix string        // WithGroup 设置的分组, 以 '.' 结尾
+	kvs    []interface{} // WithAttrs 添加的字段
+}
+
+// NewSlogHandler 返回输出到 logger 的 slog.Handler, 调用者取自 slog.Record, 分组以 '.' 连接到字段名, 例如:
+//
+//	slog.SetDefault(slog.New(log.NewSlogHandler(logger)))
+func NewSlogHandler(logger Logger) slog.Handler {
+	return &slogHandler{logger: logger}
+}
+
+func (h *slogHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return enabled(h.logger, fromSlogLevel(level))
+}
+
+func (h *slogHandler) Handle(ctx context.Context, r slog.Record) error {
+	kvs := make([]interface{}, len(h.kvs), len(h.kvs)+2*r.NumAttrs())
+	copy(kvs, h.kvs)
+	r.Attrs(func(a slog.Attr) bool {
+		kvs = appendAttr(kvs, h.prefix, a)
+		return true
+	})
+	t := r.Time
+	if t.IsZero() {
+		t = time.Now()
+	}
+	logAt(ctx, h.logger, r.PC, t, fromSlogLevel(r.Level), r.Message, kvs)
+	return nil
+}
+
+func (h *slogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	c := *h
+	c.kvs = append([]interface{}(nil), h.kvs...)
+	for _, a := range attrs {
+		c.kvs = appendAttr(c.kvs, h.prefix, a)
+	}
+	return &c
+}
+
+func (h *slogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	c := *h
+	c.prefix = h.prefix + name + "."
+	return &c
+}
+
+// appendAttr 把 a 展开为 key、value 追加到 kvs, 分组中的字段名以 '.' 连接
+func appendAttr(kvs []interface{}, prefix string, a slog.Attr) []interface{} {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return kvs
+	}
+	if a.Value.Kind() == slog.KindGroup {
+		if a.Key != "" {
+			prefix += a.Key + "."
+		}
+		for _, ga := range a.Value.Group() {
+			kvs = appendAttr(kvs, prefix, ga)
+		}
+		return kvs
+	}
+	return append(kvs, prefix+a.Key, a.Value.Any())
+}
+
+// slogLogger 输出到 slog.Handler 的 Logger
+type slogLogger struct {
+	handler slog.Handler
+	name    string
+	skip    int
+}
+
+// NewSlogLogger 返回输出到 h 的 Logger, Named 设置的名称作为 logger 字段输出, Fatalf 输出后退出程序
+func NewSlogLogger(h slog.Handler) Logger {
+	return &slogLogger{handler: h}
+}
+
+func (l *slogLogger) Config() Config {
+	return Config{}
+}
+
+func (l *slogLogger) WithOptions(opts ...Option) Logger {
+	options := &Options{}
+	for _, o := range opts {
+		o(options)
+	}
+	c := *l
+	c.skip += options.Skip
+	if len(options.Fields) > 0 {
+		attrs := make([]slog.Attr, 0, len(options.Fields))
+		for _, kv := range options.Fields {
+			attrs = append(attrs, slog.Any(kv.Key(), kv.Value()))
+		}
+		c.handler = l.handler.WithAttrs(attrs)
+	}
+	return &c
+}
+
+func (l *slogLogger) With(keyValues ...interface{}) Logger {
+	var attrs []slog.Attr
+	for i := 0; i+1 < len(keyValues); i += 2 {
+		if key, ok := keyValues[i].(string); ok {
+			attrs = append(attrs, slog.Any(key, keyValues[i+1]))
+		}
+	}
+	c := *l
+	c.handler = l.handler.WithAttrs(attrs)
+	return &c
+}
+
+func (l *slogLogger) Named(name string) Logger {
+	c := *l
+	if c.name == "" {
+		c.name = name
+	} else if name != "" {
+		c.name += "." + name
+	}
+	return &c
+}
+
+// log 输出日志, depth 为调用方相对于 log 的栈深度
+func (l *slogLogger) log(ctx context.Context, depth int, level Level, msg string, kvs []interface{}) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	lvl := toSlogLevel(level)
+	if !l.handler.Enabled(ctx, lvl) {
+		return
+	}
+	var pcs [1]uintptr
+	// 跳过 runtime.Callers 和 log
+	runtime.Callers(depth+2+l.skip, pcs[:])
+	r := slog.NewRecord(time.Now(), lvl, msg, pcs[0])
+	if l.name != "" {
+		r.AddAttrs(slog.String("logger", l.name))
+	}
+	for _, kv := range ContextFields(ctx) {
+		r.AddAttrs(slog.Any(kv.Key(), kv.Value()))
+	}
+	for i := 0; i+1 < len(kvs); i += 2 {
+		if key, ok := kvs[i].(string); ok {
+			r.AddAttrs(slog.Any(key, kvs[i+1]))
+		}
+	}
+	_ = l.handler.Handle(ctx, r)
+}
+
+// splitKvs 按 Log 的规则取出 msg 或 message 作为日志内容
+func splitKvs(kvs []interface{}) (string, []interface{}, error) {
+	if len(kvs) == 0 || len(kvs)%2 != 0 {
+		return "", nil, fmt.Errorf("kvs must appear in pairs: %v", kvs)
+	}
+	msg := ""
+	rest := make([]interface{}, 0, len(kvs))
+	for i := 0; i < len(kvs); i += 2 {
+		if key, ok := kvs[i].(string); ok && (key == "msg" || key == "message") {
+			msg = fmt.Sprint(kvs[i+1])
+			continue
+		}
+		rest = append(rest, kvs[i], kvs[i+1])
+	}
+	return msg, rest, nil
+}
+
+func (l *slogLogger) Log(level Level, kvs ...interface{}) error {
+	msg, rest, err := splitKvs(kvs)
+	if err != nil {
+		return err
+	}
+	l.log(context.Background(), 1, level, msg, rest)
+	return nil
+}
+
+func (l *slogLogger) LogCtx(ctx context.Context, level Level, kvs ...interface{}) error {
+	msg, rest, err := splitKvs(kvs)
+	if err != nil {
+		return err
+	}
+	l.log(ctx, 1, level, msg, rest)
+	return nil
+}
+
+func (l *slogLogger) Debugf(format string, v ...interface{}) {
+	l.log(context.Background(), 1, LevelDebug, fmt.Sprintf(format, v...), nil)
+}
+
+func (l *slogLogger) Infof(format string, v ...interface{}) {
+	l.log(context.Background(), 1, LevelInfo, fmt.Sprintf(format, v...), nil)
+}
+
+func (l *slogLogger) Warnf(format string, v ...interface{}) {
+	l.log(context.Background(), 1, LevelWarn, fmt.Sprintf(format, v...), nil)
+}
+
+func (l *slogLogger) Errorf(format string, v ...interface{}) {
+	l.log(context.Background(), 1, LevelError, fmt.Sprintf(format, v...), nil)
+}
+
+func (l *slogLogger) Fatalf(format string, v ...interface{}) {
+	l.log(context.Background(), 1, LevelFatal, fmt.Sprintf(format, v...), nil)
+	os.Exit(1)
+}
+
+func (l *slogLogger) Panicf(format string, v ...interface{}) {
+	msg := fmt.Sprintf(format, v...)
+	l.log(context.Background(), 1, LevelPanic, msg, nil)
+	panic(msg)
+}
+
+func (l *slogLogger) DebugCtx(ctx context.Context, format string, v ...interface{}) {
+	l.log(ctx, 1, LevelDebug, fmt.Sprintf(format, v...), nil)
+}
+
+func (l *slogLogger) InfoCtx(ctx context.Context, format string, v ...interface{}) {
+	l.log(ctx, 1, LevelInfo, fmt.Sprintf(format, v...), nil)
+}
+
+func (l *slogLogger) WarnCtx(ctx context.Context, format string, v ...interface{}) {
+	l.log(ctx, 1, LevelWarn, fmt.Sprintf(format, v...), nil)
+}
+
+func (l *slogLogger) ErrorCtx(ctx context.Context, format string, v ...interface{}) {
+	l.log(ctx, 1, LevelError, fmt.Sprintf(format, v...), nil)
+}
+
+func (l *slogLogger) Sync() error {
+	return nil
+}
+
+func (l *slogLogger) Close() error {
+	return nil
+}
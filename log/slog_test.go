@@ -0,0 +1,69 @@
+package log
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"testing"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSlogHandler(t *testing.T) {
+	l, buf := newBufferLogger(t, &Config{Level: LevelInfo, Caller: CallerConfig{Enabled: true}})
+	logger := slog.New(NewSlogHandler(l.Named("bridge"))).With("a", 1).WithGroup("g")
+	logger.Debug("ignored")
+	logger.Info("hello", "k", "v", slog.Group("sub", "x", true))
+
+	m := decodeLine(t, buf)
+	assert.Equal(t, "hello", m["message"])
+	assert.Equal(t, "bridge", m["logger"])
+	assert.Equal(t, float64(1), m["a"])
+	assert.Equal(t, "v", m["g.k"])
+	assert.Equal(t, true, m["g.sub.x"])
+	assert.Contains(t, m["caller"], "log/slog_test.go:")
+	assert.Equal(t, 0, buf.Len())
+}
+
+func TestSlogLogger(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := NewSlogLogger(slog.NewJSONHandler(buf, &slog.HandlerOptions{AddSource: true}))
+	logger.Debugf("ignored")
+	logger.Named("db").With("k", 1).Warnf("slow %s", "query")
+	assert.NoError(t, logger.Log(LevelError, "msg", "failed", "id", "x"))
+
+	m := make(map[string]interface{})
+	dec := jsoniter.NewDecoder(buf)
+	assert.NoError(t, dec.Decode(&m))
+	assert.Equal(t, "WARN", m["level"])
+	assert.Equal(t, "slow query", m["msg"])
+	assert.Equal(t, "db", m["logger"])
+	assert.Equal(t, float64(1), m["k"])
+	assert.Contains(t, m["source"].(map[string]interface{})["file"], "log/slog_test.go")
+
+	m = make(map[string]interface{})
+	assert.NoError(t, dec.Decode(&m))
+	assert.Equal(t, "ERROR", m["level"])
+	assert.Equal(t, "failed", m["msg"])
+	assert.Equal(t, "x", m["id"])
+}
+
+func TestLogr(t *testing.T) {
+	l, buf := newBufferLogger(t, &Config{Level: LevelInfo, Caller: CallerConfig{Enabled: true}})
+	logger := NewLogr(l).WithName("ctrl").WithValues("a", 1)
+	logger.V(1).Info("ignored")
+	logger.Info("reconciled", "n", 2)
+	logger.Error(errors.New("conflict"), "update failed")
+
+	m := decodeLine(t, buf)
+	assert.Equal(t, "reconciled", m["message"])
+	assert.Equal(t, "ctrl", m["logger"])
+	assert.Equal(t, float64(1), m["a"])
+	assert.Equal(t, float64(2), m["n"])
+	assert.Contains(t, m["caller"], "log/slog_test.go:")
+
+	m = decodeLine(t, buf)
+	assert.Equal(t, "error", m["level"])
+	assert.Equal(t, "conflict", m["error"].(map[string]interface{})["message"])
+}
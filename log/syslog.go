@@ -0,0 +1,161 @@
+package log
+
+import (
+	"bytes"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	defaultSyslogAddress  = "/dev/log"
+	defaultSyslogFacility = 1 // user-level messages
+)
+
+// syslogOutput 以 RFC 5424 格式通过 unix socket 写入本地 syslog
+type syslogOutput struct {
+	mu       sync.Mutex
+	address  string
+	conn     net.Conn
+	facility int
+	hostname string
+	tag      string
+	pid      string
+}
+
+func newSyslogOutput(cfg OutputConfig) (Output, error) {
+	o := &syslogOutput{
+		address:  cfg.Address,
+		facility: positive(cfg.Facility, defaultSyslogFacility),
+		tag:      cfg.Tag,
+		pid:      strconv.Itoa(os.Getpid()),
+	}
+	if o.address == "" {
+		o.address = defaultSyslogAddress
+	}
+	if o.tag == "" {
+		o.tag = filepath.Base(os.Args[0])
+	}
+	if o.hostname, _ = os.Hostname(); o.hostname == "" {
+		o.hostname = "-"
+	}
+	if err := o.connect(); err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+// connect 调用方需持有锁或在初始化时调用
+func (o *syslogOutput) connect() error {
+	conn, err := net.Dial("unixgram", o.address)
+	if err != nil {
+		return err
+	}
+	o.conn = conn
+	return nil
+}
+
+func (o *syslogOutput) Core(enc zapcore.Encoder, level zapcore.LevelEnabler) zapcore.Core {
+	return &syslogCore{LevelEnabler: level, enc: enc, out: o}
+}
+
+// severity 返回 zap 级别对应的 syslog severity
+func severity(l zapcore.Level) int {
+	switch l {
+	case zapcore.DebugLevel:
+		return 7
+	case zapcore.InfoLevel:
+		return 6
+	case zapcore.WarnLevel:
+		return 4
+	case zapcore.ErrorLevel:
+		return 3
+	default:
+		return 2
+	}
+}
+
+// write 写入一条 <PRI>1 TIMESTAMP HOSTNAME APP-NAME PROCID - - MSG 格式的日志, 连接断开时重连一次
+func (o *syslogOutput) write(ent zapcore.Entry, msg []byte) error {
+	var b bytes.Buffer
+	b.WriteByte('<')
+	b.WriteString(strconv.Itoa(o.facility*8 + severity(ent.Level)))
+	b.WriteString(">1 ")
+	b.WriteString(ent.Time.UTC().Format(time.RFC3339Nano))
+	b.WriteByte(' ')
+	b.WriteString(o.hostname)
+	b.WriteByte(' ')
+	b.WriteString(o.tag)
+	b.WriteByte(' ')
+	b.WriteString(o.pid)
+	b.WriteString(" - - ")
+	b.Write(bytes.TrimRight(msg, "\n"))
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.conn == nil {
+		if err := o.connect(); err != nil {
+			return err
+		}
+	}
+	if _, err := o.conn.Write(b.Bytes()); err != nil {
+		_ = o.conn.Close()
+		if err = o.connect(); err != nil {
+			o.conn = nil
+			return err
+		}
+		_, err = o.conn.Write(b.Bytes())
+		return err
+	}
+	return nil
+}
+
+func (o *syslogOutput) Close() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.conn == nil {
+		return nil
+	}
+	err := o.conn.Close()
+	o.conn = nil
+	return err
+}
+
+type syslogCore struct {
+	zapcore.LevelEnabler
+	enc zapcore.Encoder
+	out *syslogOutput
+}
+
+func (c *syslogCore) With(fields []zapcore.Field) zapcore.Core {
+	enc := c.enc.Clone()
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+	return &syslogCore{LevelEnabler: c.LevelEnabler, enc: enc, out: c.out}
+}
+
+func (c *syslogCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *syslogCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	buf, err := c.enc.EncodeEntry(ent, fields)
+	if err != nil {
+		return err
+	}
+	defer buf.Free()
+	return c.out.write(ent, buf.Bytes())
+}
+
+func (c *syslogCore) Sync() error {
+	return nil
+}
@@ -1,5 +1,7 @@
 package log
 
+import "github.com/neura-flow/common/types"
+
 const (
 	EncodingJSON    = "json"
 	EncodingConsole = "console"
@@ -29,6 +31,11 @@ func (l Level) LowerThan(l1 Level) bool {
 	return levels[l] < levels[l1]
 }
 
+// Enum 返回所有日志级别, 用于生成配置 schema
+func (l Level) Enum() []string {
+	return []string{string(LevelDebug), string(LevelInfo), string(LevelWarn), string(LevelError), string(LevelFatal), string(LevelPanic)}
+}
+
 var DefaultConfig = &Config{
 	Std: StdConfig{
 		Enabled: true,
@@ -63,14 +70,71 @@ type StdConfig struct {
 	Enabled bool `json:"enabled,omitempty" desc:"是否开启标准输出"`
 }
 
+type SamplingConfig struct {
+	Enabled    bool           `json:"enabled,omitempty" desc:"是否开启采样，默认不开启"`
+	Interval   types.Duration `json:"interval,omitempty" desc:"采样周期，默认 1s"`
+	First      int            `json:"first,omitempty" desc:"每个周期内相同级别、相同内容的日志先输出的条数，默认 100"`
+	Thereafter int            `json:"thereafter,omitempty" desc:"超过 first 后每 thereafter 条输出一条，默认 100"`
+}
+
+type RateLimitConfig struct {
+	Enabled  bool           `json:"enabled,omitempty" desc:"是否开启限流，默认不开启"`
+	Interval types.Duration `json:"interval,omitempty" desc:"限流周期，默认 1s"`
+	Limit    int            `json:"limit,omitempty" desc:"每个周期内同一 key 最多输出的条数，默认 100"`
+	Key      string         `json:"key,omitempty" desc:"作为限流 key 的字段名，默认按日志内容限流"`
+}
+
+type DedupConfig struct {
+	Enabled  bool           `json:"enabled,omitempty" desc:"是否合并重复日志，默认不开启"`
+	Interval types.Duration `json:"interval,omitempty" desc:"合并周期，周期内重复的日志汇总为一条 repeated N times，默认 10s"`
+}
+
+type AsyncConfig struct {
+	Enabled       bool           `json:"enabled,omitempty" desc:"是否开启异步写入，默认不开启"`
+	Size          int            `json:"size,omitempty" desc:"异步队列最多缓存的日志条数，默认 8192"`
+	Policy        string         `json:"policy,omitempty" validate:"oneof=block dropOldest dropNewest" desc:"队列满时的处理策略：block、dropOldest、dropNewest，默认 block"`
+	FlushInterval types.Duration `json:"flushInterval,omitempty" desc:"定期 flush 的间隔，默认 1s"`
+}
+
+type RedactConfig struct {
+	Enabled  bool     `json:"enabled,omitempty" desc:"是否开启脱敏，开启后屏蔽 password、token、secret、dsn 等字段以及日志中的邮箱和 URL、DSN 中的密码，默认不开启"`
+	Keys     []string `json:"keys,omitempty" desc:"额外需要屏蔽的字段名称正则，不区分大小写，匹配字段名称结尾的完整单词，如 card 匹配 creditCard、credit_card"`
+	Patterns []string `json:"patterns,omitempty" desc:"额外需要屏蔽的日志内容正则"`
+	Mask     string   `json:"mask,omitempty" desc:"屏蔽后的值，默认 ***"`
+}
+
+type OutputConfig struct {
+	Type          string         `json:"type" validate:"required" desc:"输出类型：file、syslog、tcp、udp、http 或通过 RegisterOutput 注册的类型"`
+	Level         Level          `json:"level,omitempty" desc:"该输出的最低日志级别，默认输出所有 logger 允许的日志"`
+	Encoding      string         `json:"encoding,omitempty" desc:"该输出的日志格式，默认与 encoding 相同"`
+	Path          string         `json:"path,omitempty" desc:"file 输出的日志文件路径，实际文件名带上时间，如 app.2006-01-02.log"`
+	Rotation      string         `json:"rotation,omitempty" validate:"oneof=hourly daily" desc:"file 输出的切分周期：hourly、daily，默认 daily"`
+	MaxAge        types.Duration `json:"maxAge,omitempty" desc:"file 输出的日志文件保留时长，默认不删除"`
+	Address       string         `json:"address,omitempty" desc:"syslog 输出的 unix socket 路径，默认 /dev/log；tcp、udp 输出的 host:port；http 输出的 URL"`
+	Tag           string         `json:"tag,omitempty" desc:"syslog 输出的 APP-NAME，默认为程序名"`
+	Facility      int            `json:"facility,omitempty" desc:"syslog 输出的 facility，默认 1(user)"`
+	BatchSize     int            `json:"batchSize,omitempty" desc:"tcp、udp、http 输出每批发送的日志条数，默认 100"`
+	FlushInterval types.Duration `json:"flushInterval,omitempty" desc:"tcp、udp、http 输出定期发送的间隔，默认 1s"`
+	Retries       int            `json:"retries,omitempty" desc:"tcp、udp、http 输出发送失败的重试次数，默认 3"`
+	Timeout       types.Duration `json:"timeout,omitempty" desc:"tcp、udp、http 输出每次发送的超时时间，默认 5s"`
+	BufferSize    int            `json:"bufferSize,omitempty" desc:"tcp、udp、http 输出最多缓存的日志条数，超出时丢弃最早的日志，默认 10000"`
+}
+
 type Config struct {
-	Level        Level        `json:"level,omitempty" desc:"日志级别，默认 info 级别"`
-	Encoding     string       `json:"encoding,omitempty" desc:"日志格式，默认 json"`
-	File         FileConfig   `json:"file,omitempty" desc:"日志文件配置项"`
-	Std          StdConfig    `json:"std,omitempty" desc:"标准输出配置项"`
-	Caller       CallerConfig `json:"caller,omitempty" desc:"调用者配置项"`
-	Stack        StackConfig  `json:"stack,omitempty" desc:"调用栈配置项"`
-	MessageKey   string       `json:"messageKey,omitempty" desc:"message字段key名称，默认 message"`
-	TimestampKey string       `json:"timestampKey,omitempty" desc:"timestamp字段key名称，默认timestamp"`
-	Fields       string       `json:"fields,omitempty" desc:"日志字段，格式：key1=val1,key2=val2"`
+	Level        Level            `json:"level,omitempty" desc:"日志级别，默认 info 级别"`
+	Encoding     string           `json:"encoding,omitempty" desc:"日志格式，默认 json"`
+	File         FileConfig       `json:"file,omitempty" desc:"日志文件配置项"`
+	Std          StdConfig        `json:"std,omitempty" desc:"标准输出配置项"`
+	Caller       CallerConfig     `json:"caller,omitempty" desc:"调用者配置项"`
+	Stack        StackConfig      `json:"stack,omitempty" desc:"调用栈配置项"`
+	MessageKey   string           `json:"messageKey,omitempty" desc:"message字段key名称，默认 message"`
+	TimestampKey string           `json:"timestampKey,omitempty" desc:"timestamp字段key名称，默认timestamp"`
+	Fields       string           `json:"fields,omitempty" desc:"日志字段，格式：key1=val1,key2=val2，值中可以使用 ${hostname}、${ip}、${pid}、${process}、${env:X}、${go.version}、${build.version}、${build.revision} 等占位符; 通过 config 加载时除 ${env:X} 外需写成 $${hostname} 形式, 否则会被 config 当作配置项引用"`
+	Levels       map[string]Level `json:"levels,omitempty" desc:"按 logger 名称设置日志级别，如 election: debug"`
+	Sampling     SamplingConfig   `json:"sampling,omitempty" desc:"采样配置项"`
+	RateLimit    RateLimitConfig  `json:"rateLimit,omitempty" desc:"限流配置项"`
+	Dedup        DedupConfig      `json:"dedup,omitempty" desc:"重复日志合并配置项"`
+	Async        AsyncConfig      `json:"async,omitempty" desc:"异步写入配置项"`
+	Outputs      []OutputConfig   `json:"outputs,omitempty" desc:"额外的日志输出，每个输出有独立的级别和格式"`
+	Redact       RedactConfig     `json:"redact,omitempty" desc:"脱敏配置项"`
 }
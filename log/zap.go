@@ -1,6 +1,7 @@
 package log
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"sort"
@@ -14,11 +15,21 @@ import (
 
 type ZapLogger struct {
 	cfg         Config
+	name        string
+	skip        int // WithSkip 累加的 caller skip, 重建 zap logger 时保留
 	zapCore     zapcore.Core
 	logger      *zap.Logger
 	md          metadata.Metadata
 	fields      []zap.Field
 	level       zap.AtomicLevel
+	levels      *LevelManager
+	filters     *filters
+	redactor    *redactor
+	async       *asyncWriter
+	file        *lumberjack.Logger
+	outputs     []*output
+	encoder     zapcore.Encoder
+	writeSyncer zapcore.WriteSyncer
 	encoderConf zapcore.EncoderConfig
 }
 
@@ -36,13 +47,12 @@ func NewZapLogger(cfg *Config) (Logger, error) {
 		md:  metadata.New(),
 	}
 	if cfg.Fields != "" {
-		for _, f := range strings.Split(cfg.Fields, ".") {
-			kv := strings.Split(f, "=")
-			if len(kv) == 2 {
-				if kv[0] != "" {
-					l.md.Set(kv[0], kv[1])
-				}
-			}
+		fields, err := parseFields(cfg.Fields)
+		if err != nil {
+			return nil, err
+		}
+		for _, kv := range fields {
+			l.md.Set(kv[0], kv[1])
 		}
 	}
 	if l.cfg.Encoding == "" {
@@ -60,13 +70,24 @@ func NewZapLogger(cfg *Config) (Logger, error) {
 	l.encoderConf.EncodeTime = zapcore.ISO8601TimeEncoder
 	l.encoderConf.MessageKey = l.cfg.MessageKey
 	l.encoderConf.TimeKey = l.cfg.TimestampKey
-	l.level = zap.NewAtomicLevel()
-	if level, ok := zapLevels[l.cfg.Level]; ok {
-		l.level.SetLevel(level)
-	} else {
-		l.level.SetLevel(zapcore.InfoLevel)
+	l.levels = newLevelManager(l.cfg.Level)
+	if len(l.cfg.Levels) > 0 {
+		if err := l.levels.Apply(l.cfg.Level, l.cfg.Levels); err != nil {
+			return nil, err
+		}
+	}
+	l.level = l.levels.register("")
+	l.filters = newFilters(l.cfg)
+	if l.cfg.Redact.Enabled {
+		r, err := newRedactor(l.cfg.Redact)
+		if err != nil {
+			return nil, err
+		}
+		l.redactor = r
+	}
+	if err := l.initCore(); err != nil {
+		return nil, err
 	}
-	l.initCore()
 	l.initLogger()
 	return l, nil
 }
@@ -75,32 +96,104 @@ func (l *ZapLogger) Config() Config {
 	return l.cfg
 }
 
-func (l *ZapLogger) initCore() {
+func (l *ZapLogger) initCore() error {
 	var writeSyncers []zapcore.WriteSyncer
 	if l.cfg.Std.Enabled {
-		writeSyncers = append(writeSyncers, zapcore.AddSync(os.Stderr))
+		writeSyncers = append(writeSyncers, stderrSyncer{os.Stderr})
 	}
 	if l.cfg.File.Enabled {
-		fileWriter := &lumberjack.Logger{
+		l.file = &lumberjack.Logger{
 			Filename: l.cfg.File.Path,
 			MaxSize:  l.cfg.File.MaxSize,
 			MaxAge:   l.cfg.File.MaxDays,
 			Compress: l.cfg.File.Compress,
 		}
-		writeSyncers = append(writeSyncers, zapcore.AddSync(fileWriter))
+		writeSyncers = append(writeSyncers, zapcore.AddSync(l.file))
 	}
-	var encoder zapcore.Encoder
 	if l.cfg.Encoding == EncodingJSON {
-		encoder = zapcore.NewJSONEncoder(l.encoderConf)
+		l.encoder = zapcore.NewJSONEncoder(l.encoderConf)
 	} else {
-		encoder = zapcore.NewConsoleEncoder(l.encoderConf)
+		l.encoder = zapcore.NewConsoleEncoder(l.encoderConf)
+	}
+	l.writeSyncer = zapcore.NewMultiWriteSyncer(writeSyncers...)
+	if l.cfg.Async.Enabled {
+		l.async = newAsyncWriter(l.writeSyncer, l.cfg.Async)
+		l.writeSyncer = l.async
+	}
+	if err := l.initOutputs(); err != nil {
+		return err
+	}
+	l.zapCore = l.newCore(l.level)
+	return nil
+}
+
+// stderrSyncer 忽略 stderr 为终端或管道时不支持 fsync 的错误
+type stderrSyncer struct {
+	*os.File
+}
+
+func (s stderrSyncer) Sync() error {
+	_ = s.File.Sync()
+	return nil
+}
+
+// Sync 把已写入的日志(包括异步队列中的日志以及去重的汇总)全部输出
+func (l *ZapLogger) Sync() error {
+	return l.logger.Sync()
+}
+
+// Close 输出所有日志后停止异步写入并关闭日志文件, 应在程序退出前对根 logger 调用,
+// 之后的日志同步写入
+func (l *ZapLogger) Close() error {
+	err := l.Sync()
+	if l.async != nil {
+		if e := l.async.Close(); err == nil {
+			err = e
+		}
 	}
-	writeSyncer := zapcore.NewMultiWriteSyncer(writeSyncers...)
-	l.zapCore = zapcore.NewCore(encoder, writeSyncer, l.level)
+	if l.file != nil {
+		if e := l.file.Close(); err == nil {
+			err = e
+		}
+	}
+	if e := l.closeOutputs(); err == nil {
+		err = e
+	}
+	return err
+}
+
+// AsyncStats 返回异步写入队列的状态, 未开启异步写入时返回零值
+func (l *ZapLogger) AsyncStats() AsyncStats {
+	if l.async == nil {
+		return AsyncStats{}
+	}
+	return l.async.Stats()
+}
+
+// newCore 创建使用 level 的 core 及各输出的 core, 并按配置应用采样、限流、去重以及脱敏
+func (l *ZapLogger) newCore(level zapcore.LevelEnabler) zapcore.Core {
+	core := zapcore.NewCore(l.encoder, l.writeSyncer, level)
+	if len(l.outputs) > 0 {
+		cores := []zapcore.Core{core}
+		for _, o := range l.outputs {
+			cores = append(cores, o.core(level))
+		}
+		core = zapcore.NewTee(cores...)
+	}
+	core = l.filters.wrap(core)
+	if l.redactor != nil {
+		core = &redactCore{Core: core, r: l.redactor}
+	}
+	return core
+}
+
+// DropStats 返回因采样、限流、去重被丢弃的日志条数, 根 logger 及其子 logger 共享
+func (l *ZapLogger) DropStats() DropStats {
+	return l.filters.stats()
 }
 
 func (l *ZapLogger) initLogger() {
-	var defaultSkipLevel = 1 + l.cfg.Caller.Skip
+	var defaultSkipLevel = 1 + l.cfg.Caller.Skip + l.skip
 	zapOpts := []zap.Option{
 		zap.AddCallerSkip(defaultSkipLevel),
 		zap.ErrorOutput(os.Stdout),
@@ -120,6 +213,9 @@ func (l *ZapLogger) initLogger() {
 		zapOpts = append(zapOpts, zap.Fields(fs...))
 	}
 	zlog := zap.New(l.zapCore, zapOpts...)
+	if l.name != "" {
+		zlog = zlog.Named(l.name)
+	}
 	l.logger = zlog
 	l.fields = fs
 }
@@ -151,15 +247,34 @@ func (l *ZapLogger) With(keyValues ...interface{}) Logger {
 	return &zlog
 }
 
+func (l *ZapLogger) Named(name string) Logger {
+	zlog := *l
+	zlog.md = metadata.Clone(l.md)
+	if l.name != "" {
+		zlog.name = l.name + "." + name
+	} else {
+		zlog.name = name
+	}
+	zlog.level = l.levels.register(zlog.name)
+	zlog.zapCore = l.newCore(zlog.level)
+	zlog.initLogger()
+	return &zlog
+}
+
+// Levels 返回管理根 logger 及其子 logger 级别的 LevelManager
+func (l *ZapLogger) Levels() *LevelManager {
+	return l.levels
+}
+
 func (l *ZapLogger) doWithOptions(opts *Options) {
-	if len(opts.Fields) > 0 {
-		for _, kv := range opts.Fields {
-			l.md.Set(kv.Key(), kv.Value())
-		}
-		l.initLogger()
+	for _, kv := range opts.Fields {
+		l.md.Set(kv.Key(), kv.Value())
 	}
 	if opts.Skip > 0 {
-		l.logger = l.logger.WithOptions(zap.AddCallerSkip(opts.Skip))
+		l.skip += opts.Skip
+	}
+	if len(opts.Fields) > 0 || opts.Skip > 0 {
+		l.initLogger()
 	}
 }
 
@@ -173,24 +288,46 @@ var zapLevels = map[Level]zapcore.Level{
 }
 
 func (l *ZapLogger) IsLevel(level Level) bool {
-	return l.cfg.Level == level
+	return l.levels.Level(l.name) == level
 }
 
 func (l *ZapLogger) Log(level Level, kvs ...interface{}) error {
+	zapLevel, msg, fields, ok := l.kvFields(level, kvs)
+	if !ok {
+		return nil
+	}
+	if ce := l.logger.Check(zapLevel, msg); ce != nil {
+		ce.Write(fields...)
+	}
+	return nil
+}
+
+func (l *ZapLogger) LogCtx(ctx context.Context, level Level, kvs ...interface{}) error {
+	zapLevel, msg, fields, ok := l.kvFields(level, kvs)
+	if !ok {
+		return nil
+	}
+	if ce := l.logger.Check(zapLevel, msg); ce != nil {
+		ce.Write(append(contextZapFields(ctx), fields...)...)
+	}
+	return nil
+}
+
+// kvFields 把 kvs 转换为日志内容和字段, 级别未开启或 kvs 不合法时返回 false
+func (l *ZapLogger) kvFields(level Level, kvs []interface{}) (zapcore.Level, string, []zap.Field, bool) {
 	zapLevel, ok := zapLevels[level]
 	if !ok {
 		zapLevel = zap.InfoLevel
 	}
-	if zapLevels[l.cfg.Level] > zapLevel {
-		return nil
+	if !l.level.Enabled(zapLevel) {
+		return zapLevel, "", nil, false
 	}
 	if len(kvs) == 0 || len(kvs)%2 != 0 {
 		l.Warnf("kvs must appear in pairs: %v", kvs)
-		return nil
+		return zapLevel, "", nil, false
 	}
 
 	var fields []zap.Field
-	copy(fields, l.fields)
 	msg := ""
 	for i := 0; i < len(kvs); i += 2 {
 		key, ok := kvs[i].(string)
@@ -199,16 +336,16 @@ func (l *ZapLogger) Log(level Level, kvs ...interface{}) error {
 			continue
 		}
 		if key == "msg" || key == "message" {
-			key = "message"
 			msg = fmt.Sprint(kvs[i+1])
 		} else {
-			fields = append(fields, zap.Any(key, kvs[i+1]))
+			if err, ok := kvs[i+1].(error); ok {
+				fields = append(fields, ErrorField(key, err))
+			} else {
+				fields = append(fields, zap.Any(key, kvs[i+1]))
+			}
 		}
 	}
-	if ce := l.logger.Check(zapLevel, msg); ce != nil {
-		ce.Write(fields...)
-	}
-	return nil
+	return zapLevel, msg, fields, true
 }
 
 func (l *ZapLogger) Debugf(msg string, v ...interface{}) {
@@ -234,3 +371,27 @@ func (l *ZapLogger) Fatalf(msg string, v ...interface{}) {
 func (l *ZapLogger) Panicf(msg string, v ...interface{}) {
 	l.logger.Panic(fmt.Sprintf(msg, v...))
 }
+
+func (l *ZapLogger) DebugCtx(ctx context.Context, msg string, v ...interface{}) {
+	if ce := l.logger.Check(zap.DebugLevel, fmt.Sprintf(msg, v...)); ce != nil {
+		ce.Write(contextZapFields(ctx)...)
+	}
+}
+
+func (l *ZapLogger) InfoCtx(ctx context.Context, msg string, v ...interface{}) {
+	if ce := l.logger.Check(zap.InfoLevel, fmt.Sprintf(msg, v...)); ce != nil {
+		ce.Write(contextZapFields(ctx)...)
+	}
+}
+
+func (l *ZapLogger) WarnCtx(ctx context.Context, msg string, v ...interface{}) {
+	if ce := l.logger.Check(zap.WarnLevel, fmt.Sprintf(msg, v...)); ce != nil {
+		ce.Write(contextZapFields(ctx)...)
+	}
+}
+
+func (l *ZapLogger) ErrorCtx(ctx context.Context, msg string, v ...interface{}) {
+	if ce := l.logger.Check(zap.ErrorLevel, fmt.Sprintf(msg, v...)); ce != nil {
+		ce.Write(contextZapFields(ctx)...)
+	}
+}
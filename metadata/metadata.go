@@ -25,6 +25,7 @@ const (
 	KeyLatency    = "latency"
 	KeySize       = "size"
 	KeyTraceId    = "traceId"
+	KeySpanId     = "spanId"
 )
 
 var Global Metadata = &metadata{}
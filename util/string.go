@@ -170,9 +170,11 @@ func ToString(value interface{}) string {
 	return key
 }
 
+// EmailPattern 匹配邮箱地址的正则
+const EmailPattern = `\w+([-+.]\w+)*@\w+([-.]\w+)*\.\w+([-.]\w+)*`
+
 func IsEmailFormat(email string) bool {
-	pattern := `\w+([-+.]\w+)*@\w+([-.]\w+)*\.\w+([-.]\w+)*`
-	reg := regexp.MustCompile(pattern)
+	reg := regexp.MustCompile(EmailPattern)
 	return reg.MatchString(email)
 }
 